@@ -0,0 +1,48 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	blockedKey = "expect_blocked "
+	blockedLen = len(blockedKey)
+)
+
+var (
+	errBlockedDuration  = errors.New("malformed duration in expect_blocked statement")
+	errBlockedMissQuery = errors.New("missing query in expect_blocked statement")
+	errNotBlocked       = errors.New("statement is not blocked")
+)
+
+// defaultBlockTimeout limits execution of statements in background.
+const defaultBlockTimeout = 10 * time.Second
+
+// Limit execution of async and expect_blocked statements by timeout,
+// which is set as deadline of context passed to Tx.
+func WithBlockTimeout(timeout time.Duration) Option {
+	return func(pc *parseConfig) {
+		pc.blockTimeout = timeout
+	}
+}
+
+// parseBlocked cuts expect_blocked line preceding statement.
+// It returns length of the cut line and duration during which statement should be blocked.
+func parseBlocked(src []byte) (int, time.Duration, bool, error) {
+	if !bytes.HasPrefix(src, []byte(blockedKey)) {
+		return 0, 0, false, nil
+	}
+	line, _, found := bytes.Cut(src[blockedLen:], []byte("\n"))
+	d, err := time.ParseDuration(strings.TrimSpace(string(line)))
+	if err != nil || d <= 0 {
+		return 0, 0, true, fmt.Errorf("%w: %q", errBlockedDuration, line)
+	}
+	if !found || len(bytes.TrimSpace(src[blockedLen+len(line)+1:])) == 0 {
+		return 0, 0, true, errBlockedMissQuery
+	}
+	return blockedLen + len(line) + 1, d, true, nil
+}
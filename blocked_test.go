@@ -0,0 +1,105 @@
+package sqltest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseBlocked(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantN   int
+		wantDur time.Duration
+		wantOk  bool
+		wantErr bool
+	}{
+		{name: "other", src: "SELECT 1"},
+		{name: "ok", src: "expect_blocked 200ms\nSELECT 1", wantN: 21, wantDur: 200 * time.Millisecond, wantOk: true},
+		{name: "malformed", src: "expect_blocked 200\nSELECT 1", wantOk: true, wantErr: true},
+		{name: "negative", src: "expect_blocked -1s\nSELECT 1", wantOk: true, wantErr: true},
+		{name: "missing-query", src: "expect_blocked 1s\n", wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotN, gotDur, gotOk, gotErr := parseBlocked([]byte(tt.src))
+			if gotOk != tt.wantOk {
+				t.Errorf("parseBlocked() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseBlocked() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseBlocked() succeeded unexpectedly")
+			}
+			if gotN != tt.wantN || gotDur != tt.wantDur {
+				t.Errorf("parseBlocked() = %d, %v, want %d, %v", gotN, gotDur, tt.wantN, tt.wantDur)
+			}
+		})
+	}
+}
+
+// lockTx blocks LOCK statement until COMMIT in any session.
+type lockTx struct {
+	fakeTx
+	released chan struct{}
+}
+
+func (tx *lockTx) Exec(ctx context.Context, sql string, args ...any) error {
+	switch sql {
+	case "LOCK":
+		select {
+		case <-tx.released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case "COMMIT":
+		close(tx.released)
+	}
+	return tx.fakeTx.Exec(ctx, sql, args...)
+}
+
+func TestTest_RunSessions_blocked(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name: "blocked",
+			src:  "session A;\nUPDATE 1;\nsession B;\nexpect_blocked 20ms\nLOCK;\nsession A;\nCOMMIT;\nawait B",
+		},
+		{
+			name:    "not-blocked",
+			src:     "session B;\nexpect_blocked 20ms\nUPDATE 2;\nawait",
+			wantErr: "statement is not blocked during 20ms",
+		},
+		{
+			name:    "timeout",
+			src:     "session B;\nexpect_blocked 10ms\nLOCK;\nawait",
+			opts:    []Option{WithBlockTimeout(30 * time.Millisecond)},
+			wantErr: context.DeadlineExceeded.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src), tt.opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			released := make(chan struct{})
+			gotErr := test.RunSessions(func(string) (Tx, error) {
+				return &lockTx{released: released}, nil
+			})
+			if tt.wantErr == "" && gotErr != nil || tt.wantErr != "" && (gotErr == nil || !strings.Contains(gotErr.Error(), tt.wantErr)) {
+				t.Errorf("RunSessions() error = %v, want %q", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
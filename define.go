@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +16,29 @@ const (
 	assertLen = len(assertKey)
 )
 
+// assertMode defines how returned rows compared with expected ones.
+type assertMode int
+
+const (
+	assertOrdered   assertMode = iota // rows are equal in the same order
+	assertUnordered                   // rows are equal as multisets
+	assertContains                    // expected rows are subset of returned rows
+	assertEmpty                       // no rows returned
+	assertJSON                        // returned JSON is equal structurally
+)
+
+// Keys of assert statements for comparison modes.
+var assertKeys = []struct {
+	key  string
+	mode assertMode
+}{
+	{assertKey, assertOrdered},
+	{"assert_unordered ", assertUnordered},
+	{"assert_contains ", assertContains},
+	{"assert_empty ", assertEmpty},
+	{"assert_json ", assertJSON},
+}
+
 type define struct{}
 
 var (
@@ -25,12 +50,27 @@ var (
 	errAssertWoKey  = errors.New("missing key in assert statement")
 	errAssertWoVal  = errors.New("missing expected value in assert statement")
 	errAssertUndef  = errors.New("assert uses not defined key")
+	errDefineParams = errors.New("malformed parameters list in define statement")
+	errDefineArity  = errors.New("define query placeholder exceeds parameters count")
+	errAssertArgs   = errors.New("malformed arguments list in assert statement")
+	errAssertArity  = errors.New("assert arguments count does not match define parameters")
+	errAssertBlock  = errors.New("assert expects either value on the same line or rows on the following lines")
+	errAssertEmpty  = errors.New("assert_empty does not expect value")
 )
 
+// definition is a query declared by define statement.
+type definition struct {
+	query  string
+	params []string
+}
+
 type ctxKey int
 
 const (
 	ctxKeyDefine ctxKey = iota
+	ctxKeyUpdate
+	ctxKeyVars
+	ctxKeyValues
 )
 
 func parseDefine(ctx context.Context, src string) (context.Context, error) {
@@ -46,76 +86,501 @@ func parseDefine(ctx context.Context, src string) (context.Context, error) {
 		return nil, errDefineWoQue
 	}
 	key := strings.Trim(src[defineLen:nl], " \t")
+	var params []string
+	if l := strings.IndexByte(key, '('); l >= 0 {
+		if !strings.HasSuffix(key, ")") {
+			return nil, errDefineParams
+		}
+		var err error
+		params, err = parseParams(key[l+1 : len(key)-1])
+		if err != nil {
+			return nil, err
+		}
+		key = key[:l]
+	}
 	if strings.ContainsAny(key, " \t") {
 		return nil, errDefineKeyWs
 	}
-	que := src[nl+1:]
-	var defs map[string]string
+	que, err := bindParams(src[nl+1:], params)
+	if err != nil {
+		return nil, err
+	}
+	var defs map[string]definition
 	if d := ctx.Value(ctxKeyDefine); d != nil {
-		defs = d.(map[string]string)
+		defs = d.(map[string]definition)
 		if _, ok := defs[key]; ok {
 			return nil, errDefineDouble
 		}
 	} else {
-		defs = make(map[string]string)
+		defs = make(map[string]definition)
 		ctx = context.WithValue(ctx, ctxKeyDefine, defs)
 	}
-	defs[key] = que
+	defs[key] = definition{query: que, params: params}
 	return ctx, nil
 }
 
+// parseParams parses comma separated list of define parameters names.
+func parseParams(src string) ([]string, error) {
+	params := []string{}
+	if strings.Trim(src, " \t") == "" {
+		return params, nil
+	}
+	for p := range strings.SplitSeq(src, ",") {
+		p = strings.Trim(p, " \t")
+		if p == "" || strings.ContainsAny(p, " \t") {
+			return nil, errDefineParams
+		}
+		for i := 0; i < len(p); i++ {
+			if !isIdentChar(p[i]) || p[i] == '$' {
+				return nil, errDefineParams
+			}
+		}
+		if slices.Contains(params, p) {
+			return nil, errDefineParams
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// bindParams replaces :name placeholders of define parameters with $N placeholders
+// and checks that $N placeholders are in range of parameters count.
+func bindParams(que string, params []string) (string, error) {
+	var b strings.Builder
+	var line int
+	for i := 0; i < len(que); i++ {
+		c := que[i]
+		switch c {
+		case '\'', '"':
+			j, _ := skipQuoted([]byte(que), i+1, line, c, false)
+			b.WriteString(que[i:min(j+1, len(que))])
+			i = j
+			continue
+		case '$':
+			if tag := dollarTag([]byte(que[i:])); tag != nil && (i == 0 || !isIdentChar(que[i-1])) {
+				j, _ := skipDollarQuoted([]byte(que), i+len(tag), line, tag)
+				b.WriteString(que[i:min(j+1, len(que))])
+				i = j
+				continue
+			}
+			j := i + 1
+			for j < len(que) && que[j] >= '0' && que[j] <= '9' {
+				j++
+			}
+			if j > i+1 && (i == 0 || !isIdentChar(que[i-1])) {
+				n, _ := strconv.Atoi(que[i+1 : j])
+				if n > len(params) {
+					return "", fmt.Errorf("%w: $%d", errDefineArity, n)
+				}
+			}
+		case ':':
+			if i+1 < len(que) && que[i+1] == ':' {
+				b.WriteString("::")
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(que) && isIdentChar(que[j]) && que[j] != '$' {
+				j++
+			}
+			if n := slices.Index(params, que[i+1:j]); j > i+1 && n >= 0 {
+				b.WriteByte('$')
+				b.WriteString(strconv.Itoa(n + 1))
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
 func parseAssert(ctx context.Context, src string) (Querier, error) {
 	nsrc := len(src)
-	if nsrc == assertLen {
+	mode := assertOrdered
+	for _, k := range assertKeys {
+		if strings.HasPrefix(src, k.key) {
+			mode = k.mode
+			src = src[len(k.key):]
+			break
+		}
+	}
+	if src == "" {
 		return nil, errAssertWoKey
 	}
-	src = strings.Trim(src[assertLen:], " \t")
-	ws := strings.IndexByte(src, ' ')
-	if ws == -1 {
-		return nil, errAssertWoVal
+	off := nsrc - len(src)
+	head, body, _ := strings.Cut(src, "\n")
+	key, args, want, err := parseCall(head)
+	if err != nil {
+		return nil, err
 	}
-	if ws+1 == nsrc {
+	// Span of expected value in source.
+	end := off + len(strings.TrimRight(head, " \t\r"))
+	span := [2]int{end - len(want), end}
+	if trimmed := strings.TrimLeft(body, " \t\r\n"); trimmed != "" {
+		// Block starts from the beginning of its first line.
+		start := strings.LastIndexByte(body[:len(body)-len(trimmed)], '\n') + 1
+		body = strings.TrimRight(body[start:], " \t\r\n")
+		span[0] = off + len(head) + 1 + start
+		span[1] = span[0] + len(body)
+	} else {
+		body = ""
+	}
+	switch {
+	case mode == assertEmpty:
+		if want != "" || body != "" {
+			return nil, errAssertEmpty
+		}
+	case want == "" && body == "":
 		return nil, errAssertWoVal
+	case want != "" && body != "":
+		return nil, errAssertBlock
 	}
-	key := src[:ws]
-	want := src[ws+1:]
+	d, err := lookupDefine(ctx, key, args)
+	if err != nil {
+		return nil, err
+	}
+	q := &assertQuerier{key: key, query: d.query, args: args, want: want, mode: mode, span: span, block: body != ""}
+	switch {
+	case mode == assertJSON:
+		if body != "" {
+			q.want = body
+		}
+		if !strings.Contains(q.want, "${") {
+			if _, err := parseJSON(q.want); err != nil {
+				return nil, fmt.Errorf("%w: %v", errAssertJSON, err)
+			}
+		}
+	case body != "":
+		q.columns, q.rows, err = parseTable(body)
+		if err != nil {
+			return nil, err
+		}
+	case mode == assertUnordered || mode == assertContains:
+		// Value on the same line is rows joined by spaces, like in plain assert.
+		for _, r := range splitInlineRows(want) {
+			q.rows = append(q.rows, []string{r})
+		}
+		q.want = ""
+	}
+	return q, nil
+}
+
+// splitInlineRows splits value written on the same line into rows, represented like [a b].
+// Rows are split at brackets of depth 0, so they may contain arrays like [[1 2] [3 4]].
+// Value, which is not a sequence of such rows, is a single row.
+func splitInlineRows(value string) []string {
+	var rows []string
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '[':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+			if depth == 0 {
+				rows = append(rows, value[start:i+1])
+			}
+		case depth == 0 && c != ' ' && c != '\t':
+			return []string{value}
+		}
+	}
+	if depth != 0 || rows == nil {
+		return []string{value}
+	}
+	return rows
+}
+
+// parseCall parses key of defined query with optional arguments in parentheses.
+// It returns the rest of source after the call.
+func parseCall(src string) (key string, args []any, rest string, err error) {
+	src = strings.Trim(src, " \t\r")
+	if src == "" {
+		return "", nil, "", errAssertWoKey
+	}
+	ws := strings.IndexAny(src, " \t(")
+	if ws == -1 {
+		return src, nil, "", nil
+	}
+	key, rest = src[:ws], src[ws:]
+	if rest[0] == '(' {
+		r := closingParen(rest, 0)
+		if r < 0 {
+			return "", nil, "", errAssertArgs
+		}
+		args, err = parseArgs(rest[1:r])
+		if err != nil {
+			return "", nil, "", err
+		}
+		rest = rest[r+1:]
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			return "", nil, "", errAssertArgs
+		}
+	}
+	return key, args, strings.TrimLeft(rest, " \t"), nil
+}
+
+// lookupDefine returns definition of key from context and checks arguments count.
+func lookupDefine(ctx context.Context, key string, args []any) (definition, error) {
 	def := ctx.Value(ctxKeyDefine)
 	if def == nil {
-		return nil, errAssertUndef
+		return definition{}, errAssertUndef
 	}
-	mdef := def.(map[string]string)
-	que, ok := mdef[key]
+	mdef := def.(map[string]definition)
+	d, ok := mdef[key]
 	if !ok {
-		return nil, errAssertUndef
+		return definition{}, errAssertUndef
+	}
+	if len(args) != len(d.params) {
+		return definition{}, fmt.Errorf("%w: %s takes %d arguments, got %d", errAssertArity, key, len(d.params), len(args))
 	}
-	return &assertQuerier{query: que, want: want}, nil
+	return d, nil
+}
+
+// closingParen returns index of parenthesis which closes the one at index l, or -1.
+func closingParen(src string, l int) int {
+	var depth int
+	for i := l; i < len(src); i++ {
+		switch src[i] {
+		case '\'':
+			i, _ = skipQuoted([]byte(src), i+1, 0, '\'', false)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseArgs parses comma separated list of assert arguments.
+//
+// Arguments in single quotes are unquoted, NULL is passed as nil,
+// other arguments are passed as is.
+func parseArgs(src string) ([]any, error) {
+	args := []any{}
+	if strings.Trim(src, " \t") == "" {
+		return args, nil
+	}
+	for len(src) > 0 {
+		var end int
+		for end < len(src) && src[end] != ',' {
+			if src[end] == '\'' {
+				end, _ = skipQuoted([]byte(src), end+1, 0, '\'', false)
+			}
+			end++
+		}
+		arg := strings.Trim(src[:min(end, len(src))], " \t")
+		switch {
+		case arg == "":
+			return nil, errAssertArgs
+		case arg[0] == '\'':
+			if len(arg) < 2 || arg[len(arg)-1] != '\'' {
+				return nil, errAssertArgs
+			}
+			args = append(args, strings.ReplaceAll(arg[1:len(arg)-1], "''", "'"))
+		case strings.EqualFold(arg, "NULL"):
+			args = append(args, nil)
+		default:
+			args = append(args, arg)
+		}
+		if end >= len(src) {
+			break
+		}
+		src = src[end+1:]
+		if src == "" {
+			return nil, errAssertArgs
+		}
+	}
+	return args, nil
 }
 
 type assertQuerier struct {
+	// Key of defined query, which is empty for expect statement.
+	key   string
 	query string
+	args  []any
 	want  string
+
+	// Expected rows, when assert value is written as a block of lines.
+	rows [][]string
+	// Expected columns from header of rows block.
+	// Checked only if Rows implements ColumnRows.
+	columns []string
+	mode    assertMode
+	// Expected value is written as a block of lines instead of the same line.
+	block bool
+	// Byte offsets of expected value in parsed source, which are rewritten in update mode.
+	span [2]int
 }
 
 // Query implements Querier.
 func (a *assertQuerier) Query(ctx context.Context, tx Tx) error {
-	rows, err := tx.Query(ctx, a.query)
+	if err := a.check(ctx, tx); err != nil {
+		return &assertError{key: a.key, sql: a.query, err: err}
+	}
+	return nil
+}
+
+// check runs query and compares its result with expected one.
+func (a *assertQuerier) check(ctx context.Context, tx Tx) error {
+	sql, err := expandVars(ctx, a.query)
 	if err != nil {
 		return err
 	}
-	var rs []string
-	for rows.Next() {
-		v, err := rows.String()
+	args, err := expandArgs(ctx, a.args)
+	if err != nil {
+		return err
+	}
+	if a.mode == assertJSON {
+		return a.checkJSON(ctx, tx, sql, args)
+	}
+	columns, rs, err := queryRows(ctx, tx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if u := updateFrom(ctx); u != nil && a.updatable() {
+		value, err := a.format(columns, rs)
 		if err != nil {
 			return err
 		}
-		rs = append(rs, v)
+		u.record(a.span, value)
+		return nil
 	}
-	if got := strings.Join(rs, " "); got != a.want {
-		return fmt.Errorf("defined query returns %q, want %q", got, a.want)
+	if a.columns != nil && columns != nil {
+		if err := compareColumns(columns, a.columns); err != nil {
+			return err
+		}
+	}
+	want, rows, err := a.expected(ctx)
+	if err != nil {
+		return err
+	}
+	switch a.mode {
+	case assertEmpty:
+		if len(rs) > 0 {
+			return fmt.Errorf("defined query returns %d rows, want none; first row: %s", len(rs), rs[0].text)
+		}
+		return nil
+	case assertUnordered:
+		return compareRowsUnordered(rs, rows, a.columns, false)
+	case assertContains:
+		return compareRowsUnordered(rs, rows, a.columns, true)
+	}
+	if rows != nil {
+		return compareRows(rs, rows, a.columns)
+	}
+	if got := strings.Join(rowTexts(rs), " "); got != want {
+		if len(got)+len(want) <= maxQuotedLen {
+			return fmt.Errorf("defined query returns %q, want %q", got, want)
+		}
+		return fmt.Errorf("defined query returns different rows\n%s", diffValues(rowTexts(rs), want))
 	}
 	return nil
 }
 
+// checkJSON runs query and compares returned JSON with expected document.
+func (a *assertQuerier) checkJSON(ctx context.Context, tx Tx, sql string, args []any) error {
+	texts, err := queryTexts(ctx, tx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if u := updateFrom(ctx); u != nil && a.updatable() {
+		value, err := formatJSON(texts, a.block)
+		if err != nil {
+			return err
+		}
+		u.record(a.span, value)
+		return nil
+	}
+	want, _, err := a.expected(ctx)
+	if err != nil {
+		return err
+	}
+	return compareJSON(texts, want)
+}
+
+// expected returns expected value and rows with substituted variables.
+func (a *assertQuerier) expected(ctx context.Context) (string, [][]string, error) {
+	want, err := replaceVars(ctx, a.want, false, formatVar)
+	if err != nil {
+		return "", nil, err
+	}
+	if a.rows == nil {
+		return want, nil, nil
+	}
+	rows := make([][]string, len(a.rows))
+	for i, cells := range a.rows {
+		rows[i] = make([]string, len(cells))
+		for j, cell := range cells {
+			if rows[i][j], err = replaceVars(ctx, cell, false, formatVar); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return want, rows, nil
+}
+
+// expandArgs replaces arguments, which are ${name} references, with values of variables.
+func expandArgs(ctx context.Context, args []any) ([]any, error) {
+	var expanded []any
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok || !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+			continue
+		}
+		name := strings.Trim(s[2:len(s)-1], " ")
+		val, ok := valuesFrom(ctx).get(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errVarUndef, name)
+		}
+		if expanded == nil {
+			expanded = slices.Clone(args)
+		}
+		expanded[i] = val
+	}
+	if expanded == nil {
+		return args, nil
+	}
+	return expanded, nil
+}
+
+// updatable reports whether expected value may be rewritten with actual result.
+// Values with variables references are not rewritten to keep them.
+func (a *assertQuerier) updatable() bool {
+	if a.mode == assertContains || a.mode == assertEmpty || strings.Contains(a.want, "${") {
+		return false
+	}
+	for _, cells := range a.rows {
+		for _, cell := range cells {
+			if strings.Contains(cell, "${") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// format returns expected value for actual rows in the same form as it was written.
+func (a *assertQuerier) format(columns []string, rs []row) (string, error) {
+	if !a.block {
+		return strings.Join(rowTexts(rs), " "), nil
+	}
+	if a.columns == nil {
+		return strings.Join(rowTexts(rs), "\n"), nil
+	}
+	if columns == nil {
+		columns = a.columns
+	}
+	return formatTable(columns, rs)
+}
+
 var _ Querier = (*assertQuerier)(nil)
 
 // Parse implements QueryParser.
@@ -125,11 +590,21 @@ func (d *define) Parse(ctx context.Context, src []byte) (context.Context, Querie
 		ctx, err := parseDefine(ctx, source)
 		return ctx, nil, err
 	}
-	if strings.HasPrefix(source, assertKey) {
-		query, err := parseAssert(ctx, source)
-		return nil, query, err
+	for _, k := range assertKeys {
+		if strings.HasPrefix(source, k.key) {
+			query, err := parseAssert(ctx, source)
+			return nil, query, err
+		}
 	}
 	return nil, nil, nil
 }
 
-var _ QueryParser = (*define)(nil)
+// Directives implements DirectiveParser.
+func (d *define) Directives() (header, data []string) {
+	for _, k := range assertKeys {
+		data = append(data, strings.TrimSpace(k.key))
+	}
+	return []string{strings.TrimSpace(defineKey)}, data
+}
+
+var _ DirectiveParser = (*define)(nil)
@@ -12,7 +12,7 @@ func Test_parseDefine(t *testing.T) {
 		name    string
 		ctx     context.Context
 		src     string
-		want    map[string]string
+		want    map[string]definition
 		wantErr bool
 	}{
 		{
@@ -43,25 +43,60 @@ func Test_parseDefine(t *testing.T) {
 		{
 			name:    "key-trim",
 			src:     "define   ABC\t  \nSELECT 1",
-			want:    map[string]string{"ABC": "SELECT 1"},
+			want:    map[string]definition{"ABC": {query: "SELECT 1"}},
 			wantErr: false,
 		},
 		{
 			name:    "initial-query",
 			src:     "define A\nSELECT 1",
-			want:    map[string]string{"A": "SELECT 1"},
+			want:    map[string]definition{"A": {query: "SELECT 1"}},
 			wantErr: false,
 		},
+		{
+			name: "params-named",
+			src:  "define A(uid, v)\nSELECT :uid, v::text, ':v', :v",
+			want: map[string]definition{"A": {query: "SELECT $1, v::text, ':v', $2", params: []string{"uid", "v"}}},
+		},
+		{
+			name: "params-positional",
+			src:  "define A(uid)\nSELECT $1, $$ $2 $$",
+			want: map[string]definition{"A": {query: "SELECT $1, $$ $2 $$", params: []string{"uid"}}},
+		},
+		{
+			name: "params-empty",
+			src:  "define A()\nSELECT 1",
+			want: map[string]definition{"A": {query: "SELECT 1", params: []string{}}},
+		},
+		{
+			name:    "params-arity",
+			src:     "define A(uid)\nSELECT $2",
+			wantErr: true,
+		},
+		{
+			name:    "params-duplicate",
+			src:     "define A(a, a)\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "params-unclosed",
+			src:     "define A(a\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "params-invalid",
+			src:     "define A(a b)\nSELECT 1",
+			wantErr: true,
+		},
 		{
 			name:    "append-query",
-			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]string{"B": "SELECT 2"}),
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"B": {query: "SELECT 2"}}),
 			src:     "define A\nSELECT 1",
-			want:    map[string]string{"A": "SELECT 1", "B": "SELECT 2"},
+			want:    map[string]definition{"A": {query: "SELECT 1"}, "B": {query: "SELECT 2"}},
 			wantErr: false,
 		},
 		{
 			name:    "conflict-query",
-			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]string{"A": "SELECT 2"}),
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 2"}}),
 			src:     "define A\nSELECT 1",
 			wantErr: true,
 		},
@@ -85,7 +120,7 @@ func Test_parseDefine(t *testing.T) {
 			if def == nil {
 				t.Fatal("parseDefine().Value(ctxKeyDefine) = nil")
 			}
-			mdef, ok := def.(map[string]string)
+			mdef, ok := def.(map[string]definition)
 			if !ok {
 				t.Fatalf("parseDefine().Value(ctxKeyDefine).(type) = %T", def)
 			}
@@ -120,11 +155,92 @@ func Test_parseAssert(t *testing.T) {
 			src:     "assert A []",
 			wantErr: true,
 		},
+		{
+			name: "args",
+			ctx: context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{
+				"A": {query: "SELECT $1, $2, $3", params: []string{"a", "b", "c"}},
+			}),
+			src:  "assert A(1, 'x, ''y''', null) [1 x, 'y' <nil>]",
+			want: assertQuerier{key: "A", query: "SELECT $1, $2, $3", args: []any{"1", "x, 'y'", nil}, want: "[1 x, 'y' <nil>]", span: [2]int{30, 46}},
+		},
+		{
+			name: "block",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert A\n id\n----\n  1",
+			want: assertQuerier{key: "A", query: "SELECT 1", columns: []string{"id"}, rows: [][]string{{"1"}}, block: true, span: [2]int{9, 21}},
+		},
+		{
+			name:    "block-with-value",
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:     "assert A [1]\n[1]",
+			wantErr: true,
+		},
+		{
+			name: "unordered-value",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert_unordered A [1]",
+			want: assertQuerier{key: "A", query: "SELECT 1", rows: [][]string{{"[1]"}}, mode: assertUnordered, span: [2]int{19, 22}},
+		},
+		{
+			name: "unordered-value-rows",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert_unordered A [1] [2 b]",
+			want: assertQuerier{key: "A", query: "SELECT 1", rows: [][]string{{"[1]"}, {"[2 b]"}}, mode: assertUnordered, span: [2]int{19, 28}},
+		},
+		{
+			name: "unordered-value-arrays",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert_unordered A [[1 2] [3 4]] [[5 6] [7 8]]",
+			want: assertQuerier{
+				key: "A", query: "SELECT 1", rows: [][]string{{"[[1 2] [3 4]]"}, {"[[5 6] [7 8]]"}},
+				mode: assertUnordered, span: [2]int{19, 46},
+			},
+		},
+		{
+			name: "contains-block",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert_contains A\n[1]\n[2]",
+			want: assertQuerier{key: "A", query: "SELECT 1", rows: [][]string{{"[1]"}, {"[2]"}}, mode: assertContains, block: true, span: [2]int{18, 25}},
+		},
+		{
+			name: "empty",
+			ctx:  context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:  "assert_empty A",
+			want: assertQuerier{key: "A", query: "SELECT 1", mode: assertEmpty, span: [2]int{14, 14}},
+		},
+		{
+			name:    "empty-with-value",
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:     "assert_empty A []",
+			wantErr: true,
+		},
+		{
+			name: "args-arity",
+			ctx: context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{
+				"A": {query: "SELECT $1", params: []string{"a"}},
+			}),
+			src:     "assert A(1, 2) [1]",
+			wantErr: true,
+		},
+		{
+			name:    "args-without-params",
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:     "assert A(1) [1]",
+			wantErr: true,
+		},
+		{
+			name: "args-unclosed",
+			ctx: context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{
+				"A": {query: "SELECT $1", params: []string{"a"}},
+			}),
+			src:     "assert A(1 [1]",
+			wantErr: true,
+		},
 		{
 			name:    "ok",
-			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]string{"A": "SELECT 1"}),
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
 			src:     "assert A []",
-			want:    assertQuerier{query: "SELECT 1", want: "[]"},
+			want:    assertQuerier{key: "A", query: "SELECT 1", want: "[]", span: [2]int{9, 11}},
 			wantErr: false,
 		},
 	}
@@ -189,9 +305,15 @@ func Test_define_Parse(t *testing.T) {
 			src:     "define A\nSELECT 1",
 			wantCtx: true,
 		},
+		{
+			name:    "assert-empty-ok",
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
+			src:     "assert_empty A",
+			wantQue: true,
+		},
 		{
 			name:    "assert-ok",
-			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]string{"A": "SELECT 1"}),
+			ctx:     context.WithValue(emptyCtx, ctxKeyDefine, map[string]definition{"A": {query: "SELECT 1"}}),
 			src:     "assert A []",
 			wantQue: true,
 		},
@@ -220,3 +342,106 @@ func Test_define_Parse(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []any
+		wantErr bool
+	}{
+		{name: "empty", src: " ", want: []any{}},
+		{name: "single", src: "1", want: []any{"1"}},
+		{name: "multiple", src: "1, a ,b", want: []any{"1", "a", "b"}},
+		{name: "quoted", src: "'a, b', ''", want: []any{"a, b", ""}},
+		{name: "quoted-escape", src: "'it''s'", want: []any{"it's"}},
+		{name: "null", src: "NULL, null", want: []any{nil, nil}},
+		{name: "missing-arg", src: "1,,2", wantErr: true},
+		{name: "trailing-comma", src: "1,", wantErr: true},
+		{name: "unclosed-quote", src: "'a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := parseArgs(tt.src)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseArgs() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseArgs() succeeded unexpectedly")
+			}
+			if g, w := fmt.Sprintf("%#v", got), fmt.Sprintf("%#v", tt.want); g != w {
+				t.Errorf("parseArgs() = %s, want %s", g, w)
+			}
+		})
+	}
+}
+
+func Test_assertQuerier_Query(t *testing.T) {
+	tx := &fakeTx{rows: map[string]fakeRows{
+		"plain":   {values: []string{"[1 a]"}},
+		"columns": {columns: []string{"id", "name"}, values: []string{"[1 a]"}},
+	}}
+	tests := []struct {
+		name    string
+		querier assertQuerier
+		wantErr bool
+	}{
+		{
+			name:    "value",
+			querier: assertQuerier{query: "plain", want: "[1 a]"},
+		},
+		{
+			name:    "value-differ",
+			querier: assertQuerier{query: "plain", want: "[1 b]"},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			querier: assertQuerier{query: "none", mode: assertEmpty},
+		},
+		{
+			name:    "empty-differ",
+			querier: assertQuerier{query: "plain", mode: assertEmpty},
+			wantErr: true,
+		},
+		{
+			name:    "unordered",
+			querier: assertQuerier{query: "plain", rows: [][]string{{"[1 a]"}}, mode: assertUnordered},
+		},
+		{
+			name:    "header-without-columns",
+			querier: assertQuerier{query: "plain", columns: []string{"name", "id"}, rows: [][]string{{"1", "a"}}},
+		},
+		{
+			name:    "header-columns",
+			querier: assertQuerier{query: "columns", columns: []string{"id", "name"}, rows: [][]string{{"1", "a"}}},
+		},
+		{
+			name:    "header-columns-reordered",
+			querier: assertQuerier{query: "columns", columns: []string{"name", "id"}, rows: [][]string{{"1", "a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "header-columns-renamed",
+			querier: assertQuerier{query: "columns", columns: []string{"id", "title"}, rows: [][]string{{"1", "a"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := tt.querier.Query(context.Background(), tx)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Query() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Query() succeeded unexpectedly")
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package sqltest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	maxDiffRows = 20 // rows shown in diff
+	diffContext = 3  // equal rows shown before the first difference
+
+	// Values of single line asserts are quoted in error instead of diff, if they are short.
+	maxQuotedLen = 80
+)
+
+// assertError describes failed assert with its defined query.
+type assertError struct {
+	key, sql string
+	// Location of assert, which is set by Test.queryError.
+	at  string
+	err error
+}
+
+func (e *assertError) Error() string {
+	s := &strings.Builder{}
+	if e.key != "" {
+		fmt.Fprintf(s, "assert %s", e.key)
+	} else {
+		s.WriteString("expect")
+	}
+	if e.at != "" {
+		fmt.Fprintf(s, " at %s", e.at)
+	}
+	fmt.Fprintf(s, ": %v\nquery: %s", e.err, e.sql)
+	return s.String()
+}
+
+func (e *assertError) Unwrap() error { return e.err }
+
+// diffValues returns diff of returned rows and rows of expected value written on one line.
+func diffValues(got []string, want string) string {
+	wr := splitInlineRows(want)
+	first := 0
+	for first < min(len(got), len(wr)) && got[first] == wr[first] {
+		first++
+	}
+	g, w, gotOmitted, wantOmitted := diffWindow(got, wr, first)
+	return formatDiff(cmp.Diff(w, g), gotOmitted, wantOmitted, "rows")
+}
+
+// diffRows returns diff of returned and expected rows starting near the first differing row.
+// Rows are compared by cells named after columns, if they are known.
+// Rows are compared by representations, if cells of some returned row are not known.
+func diffRows(got []row, want [][]string, columns []string, first int) string {
+	gr := make([]map[string]string, len(got))
+	for i, r := range got {
+		var cells []string
+		if columns != nil {
+			cells = r.cells(len(columns))
+		}
+		if cells == nil {
+			return diffTexts(got, want, columns, first)
+		}
+		gr[i] = namedCells(cells, columns)
+	}
+	wr := make([]map[string]string, len(want))
+	for i, cells := range want {
+		wr[i] = namedCells(cells, columns)
+	}
+	g, w, gotOmitted, wantOmitted := diffWindow(gr, wr, first)
+	return formatDiff(cmp.Diff(w, g), gotOmitted, wantOmitted, "rows")
+}
+
+// diffTexts returns diff of representations of returned and expected rows.
+func diffTexts(got []row, want [][]string, columns []string, first int) string {
+	wr := make([]string, len(want))
+	for i, cells := range want {
+		wr[i] = expectedRow(cells, columns)
+	}
+	g, w, gotOmitted, wantOmitted := diffWindow(rowTexts(got), wr, first)
+	return formatDiff(cmp.Diff(w, g), gotOmitted, wantOmitted, "rows")
+}
+
+// namedCells returns cells keyed by names of their columns.
+func namedCells(cells, columns []string) map[string]string {
+	m := make(map[string]string, len(cells))
+	for i, c := range cells {
+		name := fmt.Sprintf("column %d", i+1)
+		if i < len(columns) {
+			name = columns[i]
+		}
+		m[name] = c
+	}
+	return m
+}
+
+// diffWindow cuts returned and expected elements to window near the first difference.
+// It returns counts of omitted returned and expected elements.
+func diffWindow[T any](got, want []T, first int) (g, w []T, gotOmitted, wantOmitted int) {
+	start := max(0, first-diffContext)
+	cut := func(s []T) []T {
+		if start >= len(s) {
+			return nil
+		}
+		return s[start:min(len(s), start+maxDiffRows)]
+	}
+	g, w = cut(got), cut(want)
+	return g, w, len(got) - len(g), len(want) - len(w)
+}
+
+// formatDiff returns diff with note about omitted elements.
+func formatDiff(diff string, gotOmitted, wantOmitted int, what string) string {
+	s := "diff (-want +got):\n" + strings.TrimRight(diff, "\n")
+	if gotOmitted > 0 || wantOmitted > 0 {
+		s += fmt.Sprintf("\n... %d returned and %d expected %s are omitted", gotOmitted, wantOmitted, what)
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+package sqltest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_diffWindow(t *testing.T) {
+	got := make([]int, 100)
+	want := make([]int, 90)
+	tests := []struct {
+		first           int
+		wantStart       int
+		wantGotOmitted  int
+		wantWantOmitted int
+	}{
+		{first: 0, wantStart: 0, wantGotOmitted: 80, wantWantOmitted: 70},
+		{first: 2, wantStart: 0, wantGotOmitted: 80, wantWantOmitted: 70},
+		{first: 50, wantStart: 47, wantGotOmitted: 80, wantWantOmitted: 70},
+		{first: 85, wantStart: 82, wantGotOmitted: 82, wantWantOmitted: 82},
+		{first: 95, wantStart: 92, wantGotOmitted: 92, wantWantOmitted: 90},
+	}
+	for i := range got {
+		got[i] = i
+	}
+	for _, tt := range tests {
+		g, _, gotOmitted, wantOmitted := diffWindow(got, want, tt.first)
+		if len(g) == 0 || g[0] != tt.wantStart || gotOmitted != tt.wantGotOmitted || wantOmitted != tt.wantWantOmitted {
+			t.Errorf("diffWindow(%d) = %v..., %d and %d omitted, want start %d, %d and %d omitted",
+				tt.first, g[:1], gotOmitted, wantOmitted, tt.wantStart, tt.wantGotOmitted, tt.wantWantOmitted)
+		}
+	}
+}
+
+func TestTest_Run_diff(t *testing.T) {
+	var rows, values []string
+	for i := range 50 {
+		rows = append(rows, fmt.Sprintf("%d", i))
+		values = append(values, fmt.Sprintf("[%d]", i))
+	}
+	rows[30] = "x"
+	tests := []struct {
+		name  string
+		src   string
+		wants []string
+	}{
+		{
+			name: "short-value",
+			src:  "define two\nSELECT n FROM two;\n\n-- comment\nassert two [1]",
+			wants: []string{
+				"assert two at line 5: defined query returns \"[0] [1]\", want \"[1]\"",
+				"query: SELECT n FROM two",
+			},
+		},
+		{
+			name: "long-value",
+			src:  "define nums\nSELECT n FROM nums;\nassert nums " + strings.Join(values[:40], " "),
+			wants: []string{
+				"assert nums at line 3: defined query returns different rows\ndiff (-want +got):",
+				`"[40]",`,
+				"... 37 returned and 37 expected rows are omitted",
+			},
+		},
+		{
+			name: "rows",
+			src:  "define nums\nSELECT n FROM nums;\nassert nums\nn\n--\n" + strings.Join(rows, "\n"),
+			wants: []string{
+				"assert nums at line 3: row 31, column 1 (n): defined query returns \"30\", want \"x\"\ndiff (-want +got):",
+				`"n": "x"`,
+				"... 30 returned and 30 expected rows are omitted",
+			},
+		},
+		{
+			name:  "expect",
+			src:   "expect [1]\nSELECT n FROM nums",
+			wants: []string{"expect at line 1: defined query returns"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			tx := &fakeTx{rows: map[string]fakeRows{
+				"SELECT n FROM nums": {columns: []string{"n"}, values: values},
+				"SELECT n FROM two":  {values: values[:2]},
+			}}
+			gotErr := test.Run(tx)
+			if gotErr == nil {
+				t.Fatal("Run() succeeded unexpectedly")
+			}
+			for _, w := range tt.wants {
+				if !strings.Contains(gotErr.Error(), w) {
+					t.Errorf("Run() error = %v, want it contains %q", gotErr, w)
+				}
+			}
+		})
+	}
+}
+
+func Test_diffValues_arrays(t *testing.T) {
+	got := diffValues([]string{"[[1 2] [3 4]]", "[[5 6] [7 9]]"}, "[[1 2] [3 4]] [[5 6] [7 8]]")
+	for _, w := range []string{`"[[5 6] [7 8]]"`, `"[[5 6] [7 9]]"`} {
+		if !strings.Contains(got, w) {
+			t.Errorf("diffValues() = %q, want it contains %q", got, w)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	errMarkerDuration = errors.New("malformed duration in max-duration marker")
+	errDurationExceed = errors.New("exceeds max duration")
+)
+
+// QueryDuration is measured duration of statement.
+type QueryDuration struct {
+	// File and line of statement, counted from 1.
+	File string
+	Line int
+	// Source of statement.
+	Source string
+	// Duration of statement and its budget, which is zero if not set.
+	Duration, Max time.Duration
+}
+
+// Fail statements which take longer than max, unless they have max-duration marker.
+func WithMaxDuration(max time.Duration) Option {
+	return func(pc *parseConfig) {
+		pc.maxDuration = max
+	}
+}
+
+// Report measured durations of statements to report, even if they have no budgets.
+// Report is called after each statement, including async ones,
+// so it should be safe for concurrent use by parallel tests and background statements.
+func WithDurations(report func(QueryDuration)) Option {
+	return func(pc *parseConfig) {
+		pc.durations = report
+	}
+}
+
+// maxDuration returns budget of query q.
+func (test *Test) maxDuration(q query) time.Duration {
+	switch {
+	case q.markers.maxDuration > 0:
+		return q.markers.maxDuration
+	case test.markers.maxDuration > 0:
+		return test.markers.maxDuration
+	}
+	return test.budget
+}
+
+// measure calls query and checks its duration.
+func (test *Test) measure(q query, call func() error) error {
+	start := time.Now()
+	err := call()
+	d := QueryDuration{
+		File:     q.file,
+		Line:     q.left.line + bytes.Count(q.source[:q.offset], []byte("\n")) + 1,
+		Source:   string(q.source),
+		Duration: time.Since(start),
+		Max:      test.maxDuration(q),
+	}
+	if test.durations != nil {
+		test.durations(d)
+	}
+	if err == nil && d.Max > 0 && d.Duration > d.Max {
+		return fmt.Errorf("statement takes %v, it %w %v", d.Duration, errDurationExceed, d.Max)
+	}
+	return err
+}
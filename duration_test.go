@@ -0,0 +1,99 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowTx sleeps on SLOW statement.
+type slowTx struct {
+	fakeTx
+}
+
+func (tx *slowTx) Exec(ctx context.Context, sql string, args ...any) error {
+	if strings.HasSuffix(sql, "SLOW") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return tx.fakeTx.Exec(ctx, sql, args...)
+}
+
+func TestTest_Run_maxDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "no-budget", src: "SELECT 1;\nSLOW"},
+		{name: "statement", src: "SELECT 1;\n-- sqltest:max-duration 5ms\nSLOW", wantErr: true},
+		{name: "statement-fast", src: "SELECT 0;\n-- sqltest:max-duration 5ms\nSELECT 1;\nSLOW"},
+		{name: "file", src: "-- sqltest:max-duration 5ms\n\nSELECT 1;\nSLOW", wantErr: true},
+		{name: "option", src: "SELECT 1;\nSLOW", opts: []Option{WithMaxDuration(5 * time.Millisecond)}, wantErr: true},
+		{
+			name: "option-overridden",
+			src:  "SELECT 1;\n-- sqltest:max-duration 1s\nSLOW",
+			opts: []Option{WithMaxDuration(5 * time.Millisecond)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src), tt.opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			gotErr := test.Run(&slowTx{})
+			if gotErr != nil {
+				if !tt.wantErr || !strings.Contains(gotErr.Error(), errDurationExceed.Error()) {
+					t.Errorf("Run() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Run() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func TestWithDurations(t *testing.T) {
+	var got []QueryDuration
+	test, err := New(strings.NewReader("SELECT 1;\n-- sqltest:max-duration 1s\nSLOW"), WithDurations(func(d QueryDuration) {
+		got = append(got, d)
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if err := test.Run(&slowTx{}); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Run() reports %d durations, want 2", len(got))
+	}
+	if d := got[1]; d.Line != 3 || d.Max != time.Second || d.Duration < 20*time.Millisecond {
+		t.Errorf("Run() reports %+v, want line 3 with max 1s and duration at least 20ms", d)
+	}
+}
+
+func TestWithDurations_async(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	src := "SELECT 1;\n\n-- statement\nasync\nSLOW;\nawait"
+	test, err := New(strings.NewReader(src), WithDurations(func(d QueryDuration) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, fmt.Sprintf("%d %s", d.Line, strings.TrimSpace(d.Source[strings.LastIndex(d.Source, "\n")+1:])))
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if err := test.RunSessions(func(string) (Tx, error) { return &slowTx{}, nil }); err != nil {
+		t.Fatalf("RunSessions() failed: %v", err)
+	}
+	if g, w := strings.Join(got, ", "), "1 SELECT 1, 5 SLOW"; g != w {
+		t.Errorf("RunSessions() reports %q, want %q", g, w)
+	}
+}
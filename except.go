@@ -5,6 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -13,14 +16,50 @@ type except struct{}
 const (
 	exceptKey = "except "
 	exceptLen = len(exceptKey)
+
+	sqlstatePrefix = "sqlstate:"
+	regexpPrefix   = "re:"
 )
 
+// errorFields are names of fields which may be matched by except statement.
+var errorFields = []string{
+	"message",
+	"detail",
+	"hint",
+	"where",
+	"schema",
+	"table",
+	"column",
+	"datatype",
+	"constraint",
+}
+
 var (
 	errExceptMissQuery = errors.New("missing query in except statement")
 	errExceptMissStr   = errors.New("missing exception substring in except statement")
 	errExceptNoError   = errors.New("expected an error but query succeeded unexpectedly")
+	errExceptState     = errors.New("malformed SQLSTATE in except statement")
+	errExceptTerm      = errors.New("malformed term in except statement")
+	errExceptField     = errors.New("unknown error field in except statement")
 )
 
+// SQLStateError is optionally implemented by errors returned from [Tx]
+// to expose SQLSTATE code, which is matched by except sqlstate:CODE statements.
+type SQLStateError interface {
+	error
+	SQLState() string
+}
+
+// FieldError is optionally implemented by errors returned from [Tx]
+// to expose structured fields, which are matched by except FIELD=VALUE statements.
+// Field names are message, detail, hint, where, schema, table, column, datatype and constraint.
+type FieldError interface {
+	error
+
+	// ErrorField returns value of named field and whether error has it.
+	ErrorField(name string) (string, bool)
+}
+
 // Parse implements QueryParser.
 func (e *except) Parse(ctx context.Context, src []byte) (context.Context, Querier, error) {
 	if !bytes.HasPrefix(src, []byte(exceptKey)) {
@@ -36,27 +75,264 @@ func (e *except) Parse(ctx context.Context, src []byte) (context.Context, Querie
 	if nl+1 == len(src) {
 		return nil, nil, errExceptMissQuery
 	}
-	return nil, &exceptQuerier{
-		query:  string(src[nl+1:]),
-		except: string(src[exceptLen:nl]),
-	}, nil
+	q := &exceptQuerier{query: string(src[nl+1:])}
+	spec := string(src[exceptLen:nl])
+	if !structuredExcept(spec) {
+		q.except = spec
+		return nil, q, nil
+	}
+	var err error
+	q.sqlstate, q.matchers, err = parseExceptTerms(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, q, nil
+}
+
+// structuredExcept reports whether except spec starts with a term
+// instead of being a plain message substring.
+func structuredExcept(spec string) bool {
+	if strings.HasPrefix(spec, sqlstatePrefix) || strings.HasPrefix(spec, regexpPrefix) {
+		return true
+	}
+	i := strings.IndexAny(spec, "=~")
+	return i > 0 && slices.Contains(errorFields, spec[:i])
 }
 
-var _ QueryParser = (*except)(nil)
+// parseExceptTerms parses whitespace separated terms of except statement:
+//
+//	sqlstate:CODE  SQLSTATE code or class (23*)
+//	re:PATTERN     regular expression matched with error message
+//	FIELD=VALUE    value of error field
+//	FIELD~PATTERN  regular expression matched with value of error field
+//
+// Values containing whitespaces should be double quoted with Go syntax.
+func parseExceptTerms(spec string) (sqlstate string, matchers []errMatcher, err error) {
+	for {
+		spec = strings.TrimLeft(spec, " \t\r")
+		if spec == "" {
+			return sqlstate, matchers, nil
+		}
+		var m errMatcher
+		switch {
+		case strings.HasPrefix(spec, sqlstatePrefix):
+			spec = spec[len(sqlstatePrefix):]
+			m.field = "sqlstate"
+		case strings.HasPrefix(spec, regexpPrefix):
+			spec = spec[len(regexpPrefix):]
+			m.field, m.regexp = "message", true
+		default:
+			i := strings.IndexAny(spec, "=~ \t")
+			if i <= 0 || spec[i] == ' ' || spec[i] == '\t' {
+				return "", nil, fmt.Errorf("%w: %q", errExceptTerm, spec)
+			}
+			m.field, m.regexp = spec[:i], spec[i] == '~'
+			if !slices.Contains(errorFields, m.field) {
+				return "", nil, fmt.Errorf("%w: %q", errExceptField, m.field)
+			}
+			spec = spec[i+1:]
+		}
+		m.value, spec, err = cutTermValue(spec)
+		if err != nil {
+			return "", nil, err
+		}
+		if m.field == "sqlstate" {
+			if sqlstate != "" || !validSQLState(m.value) {
+				return "", nil, fmt.Errorf("%w: %q", errExceptState, m.value)
+			}
+			sqlstate = m.value
+			continue
+		}
+		if m.regexp {
+			if m.re, err = regexp.Compile(m.value); err != nil {
+				return "", nil, fmt.Errorf("%w: %v", errExceptTerm, err)
+			}
+		}
+		matchers = append(matchers, m)
+	}
+}
+
+// cutTermValue returns bare or double quoted value at the start of src and the rest of src.
+func cutTermValue(src string) (value, rest string, err error) {
+	if strings.HasPrefix(src, `"`) {
+		quoted, err := strconv.QuotedPrefix(src)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %q", errExceptTerm, src)
+		}
+		value, _ = strconv.Unquote(quoted)
+		return value, src[len(quoted):], nil
+	}
+	i := strings.IndexAny(src, " \t\r")
+	if i < 0 {
+		i = len(src)
+	}
+	if i == 0 {
+		return "", "", fmt.Errorf("%w: missing value", errExceptTerm)
+	}
+	return src[:i], src[i:], nil
+}
+
+// Directives implements DirectiveParser.
+func (e *except) Directives() (header, data []string) {
+	return []string{strings.TrimSpace(exceptKey)}, nil
+}
+
+var _ DirectiveParser = (*except)(nil)
+
+// validSQLState reports whether code is five characters SQLSTATE
+// or its prefix (class) followed by *.
+func validSQLState(code string) bool {
+	prefix, class := strings.CutSuffix(code, "*")
+	if class && (len(prefix) < 2 || len(prefix) > 4) || !class && len(code) != 5 {
+		return false
+	}
+	for _, c := range []byte(prefix) {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
 
 type exceptQuerier struct {
-	query  string
-	except string
+	query    string
+	except   string
+	sqlstate string
+	matchers []errMatcher
+}
+
+// errMatcher matches value of error field.
+type errMatcher struct {
+	field  string
+	value  string
+	regexp bool
+	re     *regexp.Regexp
+}
+
+func (m errMatcher) String() string {
+	op := "="
+	if m.regexp {
+		op = "~"
+	}
+	return m.field + op + strconv.Quote(m.value)
+}
+
+func (m errMatcher) match(err error) bool {
+	v, ok := errorField(err, m.field)
+	if !ok {
+		return false
+	}
+	if m.re != nil {
+		return m.re.MatchString(v)
+	}
+	return v == m.value
+}
+
+// errorField returns value of named field of err.
+// Message is taken from err itself, if it does not implement FieldError.
+func errorField(err error, name string) (string, bool) {
+	var fe FieldError
+	if errors.As(err, &fe) {
+		if v, ok := fe.ErrorField(name); ok {
+			return v, true
+		}
+	}
+	if name == "message" {
+		return err.Error(), true
+	}
+	return "", false
+}
+
+// describeError returns all known fields of err, one per line.
+func describeError(err error) string {
+	s := &strings.Builder{}
+	var se SQLStateError
+	if errors.As(err, &se) {
+		fmt.Fprintf(s, "\n\tsqlstate: %s", se.SQLState())
+	}
+	for _, name := range errorFields {
+		if v, ok := errorField(err, name); ok {
+			fmt.Fprintf(s, "\n\t%s: %s", name, v)
+		}
+	}
+	return s.String()
 }
 
 // Query implements Querier.
 func (e *exceptQuerier) Query(ctx context.Context, tx Tx) error {
-	err := tx.Exec(ctx, e.query)
+	sql, err := expandVars(ctx, e.query)
+	if err != nil {
+		return err
+	}
+	err = tx.Exec(ctx, sql)
 	if err == nil {
 		return errExceptNoError
 	}
+	if e.sqlstate != "" {
+		if err := matchSQLState(err, e.sqlstate); err != nil {
+			return err
+		}
+	}
+	for _, m := range e.matchers {
+		if !m.match(err) {
+			return fmt.Errorf("expected error with %s, but got:%s", m, describeError(err))
+		}
+	}
+	if e.sqlstate != "" || e.matchers != nil {
+		return nil
+	}
 	if strings.Contains(err.Error(), e.except) {
 		return nil
 	}
 	return fmt.Errorf("expected error contained %q, but got %v", e.except, err)
 }
+
+// ExpectsError implements FailingQuerier.
+func (e *exceptQuerier) ExpectsError() bool {
+	return true
+}
+
+var _ FailingQuerier = (*exceptQuerier)(nil)
+
+// matchSQLState checks SQLSTATE of err with code or class pattern.
+// If err does not implement SQLStateError, error message is searched for a code,
+// which is a whole five characters word equal to code or starting with class.
+func matchSQLState(err error, code string) error {
+	prefix, class := strings.CutSuffix(code, "*")
+	var se SQLStateError
+	if !errors.As(err, &se) {
+		if containsCode(err.Error(), prefix, class) {
+			return nil
+		}
+		return fmt.Errorf("expected error with SQLSTATE %s, but got %v", code, err)
+	}
+	state := se.SQLState()
+	if state == code || class && strings.HasPrefix(state, prefix) {
+		return nil
+	}
+	return fmt.Errorf("expected error with SQLSTATE %s, but got SQLSTATE %s: %v", code, state, err)
+}
+
+// containsCode reports whether msg contains five characters word equal to code,
+// or starting with code if it is a class.
+func containsCode(msg, code string, class bool) bool {
+	for i := 0; i < len(msg); {
+		if !isCodeChar(msg[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(msg) && isCodeChar(msg[i]) {
+			i++
+		}
+		word := msg[start:i]
+		if len(word) == 5 && (word == code || class && strings.HasPrefix(word, code)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCodeChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
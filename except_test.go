@@ -2,16 +2,25 @@ package sqltest
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 )
 
 func Test_except_Parse(t *testing.T) {
 	cmp := func(v Querier) string {
-		if v == nil {
+		p, _ := v.(*exceptQuerier)
+		if p == nil {
 			return "<nil>"
 		}
-		return fmt.Sprintf("%+v", v.(*exceptQuerier))
+		q := *p
+		ms := make([]string, len(q.matchers))
+		for i, m := range q.matchers {
+			ms[i] = m.String()
+		}
+		q.matchers = nil
+		return fmt.Sprintf("%+v%v", q, ms)
 	}
 
 	tests := []struct {
@@ -40,6 +49,75 @@ func Test_except_Parse(t *testing.T) {
 			src:     "except ERROR\n",
 			wantErr: true,
 		},
+		{
+			name:    "sqlstate",
+			src:     "except sqlstate:23505\nSELECT 1",
+			wantQue: &exceptQuerier{query: "SELECT 1", sqlstate: "23505"},
+		},
+		{
+			name:    "sqlstate-class",
+			src:     "except sqlstate:23*\nSELECT 1",
+			wantQue: &exceptQuerier{query: "SELECT 1", sqlstate: "23*"},
+		},
+		{
+			name:    "sqlstate-malformed",
+			src:     "except sqlstate:2350\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "sqlstate-empty-class",
+			src:     "except sqlstate:*\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "sqlstate-short-class",
+			src:     "except sqlstate:2*\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "sqlstate-malformed-class",
+			src:     "except sqlstate:23505*\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name: "terms",
+			src:  "except sqlstate:23505 constraint=emp_pkey detail~\"Key \\\\(id\\\\)=\" re:duplicate\nSELECT 1",
+			wantQue: &exceptQuerier{query: "SELECT 1", sqlstate: "23505", matchers: []errMatcher{
+				{field: "constraint", value: "emp_pkey"},
+				{field: "detail", value: `Key \(id\)=`, regexp: true},
+				{field: "message", value: "duplicate", regexp: true},
+			}},
+		},
+		{
+			name:    "terms-substring",
+			src:     "except unknown=value\nSELECT 1",
+			wantQue: &exceptQuerier{query: "SELECT 1", except: "unknown=value"},
+		},
+		{
+			name:    "terms-invalid-regexp",
+			src:     "except re:(\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "terms-unknown-field",
+			src:     "except hint=a severity=ERROR\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "terms-malformed",
+			src:     "except hint=a b\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "terms-unclosed-quote",
+			src:     "except hint=\"a\nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "terms-missing-value",
+			src:     "except hint= \nSELECT 1",
+			wantErr: true,
+		},
 		{
 			name:    "ok",
 			src:     "except ERROR\nSELECT 1",
@@ -64,3 +142,86 @@ func Test_except_Parse(t *testing.T) {
 		})
 	}
 }
+
+// stateError is an error with SQLSTATE code.
+type stateError struct {
+	msg, code string
+}
+
+func (e *stateError) Error() string    { return e.msg }
+func (e *stateError) SQLState() string { return e.code }
+
+var _ SQLStateError = (*stateError)(nil)
+
+// fieldError is an error with structured fields.
+type fieldError struct {
+	stateError
+	fields map[string]string
+}
+
+func (e *fieldError) ErrorField(name string) (string, bool) {
+	v, ok := e.fields[name]
+	return v, ok
+}
+
+var _ FieldError = (*fieldError)(nil)
+
+func Test_exceptQuerier_Query(t *testing.T) {
+	tx := &fakeTx{errs: map[string]error{
+		"unique": fmt.Errorf("exec: %w", &stateError{msg: "duplicate key value", code: "23505"}),
+		"plain":  errors.New("duplicate key value (SQLSTATE 23505)"),
+		"range":  errors.New("value 23 out of range (SQLSTATE 123505)"),
+		"fields": &fieldError{
+			stateError: stateError{msg: "ERROR: duplicate key value (SQLSTATE 23505)", code: "23505"},
+			fields:     map[string]string{"message": "duplicate key value", "constraint": "emp_pkey"},
+		},
+	}}
+	tests := []struct {
+		name    string
+		querier exceptQuerier
+		wantErr bool
+	}{
+		{name: "no-error", querier: exceptQuerier{query: "ok", except: "ERROR"}, wantErr: true},
+		{name: "substring", querier: exceptQuerier{query: "unique", except: "duplicate"}},
+		{name: "substring-differ", querier: exceptQuerier{query: "unique", except: "violates"}, wantErr: true},
+		{name: "sqlstate", querier: exceptQuerier{query: "unique", sqlstate: "23505"}},
+		{name: "sqlstate-class", querier: exceptQuerier{query: "unique", sqlstate: "23*"}},
+		{name: "sqlstate-differ", querier: exceptQuerier{query: "unique", sqlstate: "23503"}, wantErr: true},
+		{name: "sqlstate-class-differ", querier: exceptQuerier{query: "unique", sqlstate: "22*"}, wantErr: true},
+		{name: "field", querier: exceptQuerier{query: "fields", sqlstate: "23505", matchers: []errMatcher{
+			{field: "constraint", value: "emp_pkey"},
+		}}},
+		{name: "field-differ", querier: exceptQuerier{query: "fields", matchers: []errMatcher{
+			{field: "constraint", value: "emp_key"},
+		}}, wantErr: true},
+		{name: "field-missing", querier: exceptQuerier{query: "fields", matchers: []errMatcher{
+			{field: "hint", value: "emp_pkey"},
+		}}, wantErr: true},
+		{name: "field-regexp", querier: exceptQuerier{query: "fields", matchers: []errMatcher{
+			{field: "message", regexp: true, re: regexp.MustCompile(`^duplicate`)},
+		}}},
+		{name: "message-regexp", querier: exceptQuerier{query: "plain", matchers: []errMatcher{
+			{field: "message", regexp: true, re: regexp.MustCompile(`SQLSTATE 2350\d`)},
+		}}},
+		{name: "sqlstate-fallback", querier: exceptQuerier{query: "plain", sqlstate: "23505"}},
+		{name: "sqlstate-fallback-differ", querier: exceptQuerier{query: "plain", sqlstate: "23503"}, wantErr: true},
+		{name: "sqlstate-fallback-class", querier: exceptQuerier{query: "plain", sqlstate: "23*"}},
+		{name: "sqlstate-fallback-class-differ", querier: exceptQuerier{query: "plain", sqlstate: "22*"}, wantErr: true},
+		{name: "sqlstate-fallback-class-word", querier: exceptQuerier{query: "range", sqlstate: "23*"}, wantErr: true},
+		{name: "sqlstate-fallback-word", querier: exceptQuerier{query: "range", sqlstate: "23505"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := tt.querier.Query(context.Background(), tx)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Query() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Query() succeeded unexpectedly")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+)
+
+// expect parses inline asserts, which do not need define statement.
+type expect struct{}
+
+const (
+	expectKey = "expect "
+	expectLen = len(expectKey)
+)
+
+var (
+	errExpectMissQuery = errors.New("missing query in expect statement")
+	errExpectMissVal   = errors.New("missing expected value in expect statement")
+)
+
+// Parse implements QueryParser.
+func (e *expect) Parse(ctx context.Context, src []byte) (context.Context, Querier, error) {
+	if !bytes.HasPrefix(src, []byte(expectKey)) {
+		return nil, nil, nil
+	}
+	nl := bytes.IndexByte(src, '\n')
+	if nl == -1 {
+		return nil, nil, errExpectMissQuery
+	}
+	head := strings.TrimRight(string(src[expectLen:nl]), " \t\r")
+	want := strings.TrimLeft(head, " \t")
+	if want == "" {
+		return nil, nil, errExpectMissVal
+	}
+	end := expectLen + len(head)
+	if nl+1 == len(src) {
+		return nil, nil, errExpectMissQuery
+	}
+	return nil, &assertQuerier{
+		query: string(src[nl+1:]),
+		want:  want,
+		span:  [2]int{end - len(want), end},
+	}, nil
+}
+
+// Directives implements DirectiveParser.
+func (e *expect) Directives() (header, data []string) {
+	return []string{strings.TrimSpace(expectKey)}, nil
+}
+
+var _ DirectiveParser = (*expect)(nil)
@@ -0,0 +1,73 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func Test_expect_Parse(t *testing.T) {
+	cmp := func(v Querier) string {
+		p, _ := v.(*assertQuerier)
+		if p == nil {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%+v", *p)
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		wantQue *assertQuerier
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			src:  "",
+		},
+		{
+			name: "unrecognized",
+			src:  "expected [1]\nSELECT 1",
+		},
+		{
+			name:    "missing-value",
+			src:     "expect \nSELECT 1",
+			wantErr: true,
+		},
+		{
+			name:    "missing-query",
+			src:     "expect [1]",
+			wantErr: true,
+		},
+		{
+			name:    "missing-query-with-nl",
+			src:     "expect [1]\n",
+			wantErr: true,
+		},
+		{
+			name:    "ok",
+			src:     "expect  [1 125800] \nSELECT user_id, salary\nFROM emp",
+			wantQue: &assertQuerier{query: "SELECT user_id, salary\nFROM emp", want: "[1 125800]", span: [2]int{8, 18}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCtx, gotQue, gotErr := (&expect{}).Parse(context.Background(), []byte(tt.src))
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Parse() succeeded unexpectedly")
+			}
+			if gotCtx != nil {
+				t.Error("Parse() returns context")
+			}
+			if g, w := cmp(gotQue), cmp(tt.wantQue); g != w {
+				t.Errorf("Parse() querier = %s, want %s", g, w)
+			}
+		})
+	}
+}
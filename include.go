@@ -0,0 +1,48 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	includeKey = "include "
+	includeLen = len(includeKey)
+)
+
+var (
+	errIncludeMissPath = errors.New("missing path in include statement")
+	errIncludeCycle    = errors.New("include cycle")
+)
+
+// parseInclude returns path from include statement.
+func parseInclude(src []byte) (string, bool) {
+	if !bytes.HasPrefix(src, []byte(includeKey)) {
+		return "", false
+	}
+	return strings.Trim(string(src[includeLen:]), " \t\r\n"), true
+}
+
+// include parses queries of file name, which is relative to the including file.
+func (test *Test) include(config *parseConfig, file, name string, includes []string) error {
+	if name == "" {
+		return errIncludeMissPath
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(file), name)
+	}
+	name = filepath.Clean(name)
+	if slices.Contains(includes, name) {
+		return fmt.Errorf("%w: %s", errIncludeCycle, strings.Join(append(includes, name), " -> "))
+	}
+	source, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return test.parse(config, name, source, append(includes[:len(includes):len(includes)], name), false)
+}
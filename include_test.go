@@ -0,0 +1,103 @@
+package sqltest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_include(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "fixture",
+			file: "testdata/include/main.sql",
+			want: "{l: 0.0 r: 0.33 INSERT INTO users VALUES (1), (2) *execQuerier{INSERT INTO users VALUES (1), (2)}}" +
+				"  {l: 2.29 r: 2.50 assert user_count [2] *assertQuerier{query: SELECT count(*) FROM users, want: [2]}}",
+		},
+		{
+			name: "cycle",
+			file: "testdata/include/cycle_a.sql",
+			wantErr: "testdata/include/cycle_a.sql:2: testdata/include/cycle_b.sql:1: include cycle: " +
+				"testdata/include/cycle_a.sql -> testdata/include/cycle_b.sql -> testdata/include/cycle_a.sql",
+		},
+		{
+			name:    "included-position",
+			file:    "testdata/include/broken.sql",
+			wantErr: "testdata/include/broken.sql:2: testdata/include/fixtures/broken.sql:3: assert uses not defined key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, gotErr := New(f)
+			if gotErr != nil {
+				if g := gotErr.Error(); g != tt.wantErr {
+					t.Errorf("New() error = %q, want %q", g, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("New() succeeded unexpectedly")
+			}
+			if g := cmpQueries(got.queries); g != tt.want {
+				t.Errorf("New() = %q, want %q", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestTest_Run_include(t *testing.T) {
+	f, err := os.Open("testdata/include/main.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	test, err := New(f)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{errs: map[string]error{"INSERT INTO users VALUES (1), (2)": errors.New("no table")}}
+	err = test.Run(tx)
+	if err == nil {
+		t.Fatal("Run() succeeded unexpectedly")
+	}
+	if g, w := err.Error(), "Query in testdata/include/fixtures/users.sql on lines 1:1"; !strings.HasPrefix(g, w) {
+		t.Errorf("Run() error = %q, want prefix %q", g, w)
+	}
+}
+
+func TestNew_includeLarge(t *testing.T) {
+	dir := t.TempDir()
+	fixture := strings.Repeat("SELECT 1;\n", 70)
+	if err := os.WriteFile(filepath.Join(dir, "fixture.sql"), []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	main := "include fixture.sql;\n" + strings.Repeat("SELECT 2;\n", 39)
+	name := filepath.Join(dir, "main.sql")
+	if err := os.WriteFile(name, []byte(main), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	test, err := New(f)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if g, w := len(test.queries), 109; g != w {
+		t.Errorf("New() parses %d queries, want %d", g, w)
+	}
+}
@@ -0,0 +1,155 @@
+package sqltest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	errAssertJSON = errors.New("expected value of assert_json is not JSON")
+	errQueryJSON  = errors.New("defined query returns not JSON")
+)
+
+// parseJSON parses JSON document. Numbers are kept as [json.Number],
+// so big integers and long decimals are not rounded to float64.
+func parseJSON(src string) (any, error) {
+	d := json.NewDecoder(strings.NewReader(src))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON after top-level value")
+	}
+	return v, nil
+}
+
+// equalNumbers compares JSON numbers by their exact values, so 1, 1.0 and 1e0 are equal.
+var equalNumbers = cmp.Comparer(func(x, y json.Number) bool {
+	rx, okx := new(big.Rat).SetString(string(x))
+	ry, oky := new(big.Rat).SetString(string(y))
+	if !okx || !oky {
+		return x == y
+	}
+	return rx.Cmp(ry) == 0
+})
+
+// returnedJSON parses texts of returned rows as JSON document.
+// Single row is the document itself, several rows are elements of array.
+func returnedJSON(rs []string) (any, error) {
+	docs := make([]any, len(rs))
+	for i, r := range rs {
+		v, err := parseJSON(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", errQueryJSON, i+1, err)
+		}
+		docs[i] = v
+	}
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// compareJSON compares returned rows with expected document structurally
+// and reports differences by their paths.
+func compareJSON(rs []string, want string) error {
+	w, err := parseJSON(want)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errAssertJSON, err)
+	}
+	g, err := returnedJSON(rs)
+	if err != nil {
+		return err
+	}
+	r := &jsonReporter{}
+	if cmp.Equal(g, w, equalNumbers, cmp.Reporter(r)) {
+		return nil
+	}
+	return fmt.Errorf("defined query returns JSON differing from expected:\n%s", strings.Join(r.diffs, "\n"))
+}
+
+// formatJSON returns returned rows as JSON document, indented if it is written as block.
+func formatJSON(rs []string, block bool) (string, error) {
+	v, err := returnedJSON(rs)
+	if err != nil {
+		return "", err
+	}
+	var b []byte
+	if block {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	return string(b), err
+}
+
+// jsonReporter collects differences of JSON documents with their paths.
+type jsonReporter struct {
+	path  cmp.Path
+	diffs []string
+}
+
+func (r *jsonReporter) PushStep(ps cmp.PathStep) { r.path = append(r.path, ps) }
+
+func (r *jsonReporter) PopStep() { r.path = r.path[:len(r.path)-1] }
+
+func (r *jsonReporter) Report(rs cmp.Result) {
+	if rs.Equal() {
+		return
+	}
+	got, want := r.path.Last().Values()
+	var diff string
+	switch {
+	case !got.IsValid():
+		diff = "missing, want " + jsonText(want.Interface())
+	case !want.IsValid():
+		diff = "unexpected " + jsonText(got.Interface())
+	default:
+		diff = jsonText(got.Interface()) + ", want " + jsonText(want.Interface())
+	}
+	r.diffs = append(r.diffs, jsonPath(r.path)+": "+diff)
+}
+
+var jsonIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPath formats path like $.items[2].price.
+func jsonPath(path cmp.Path) string {
+	s := &strings.Builder{}
+	s.WriteByte('$')
+	for _, step := range path {
+		switch step := step.(type) {
+		case cmp.MapIndex:
+			key := step.Key().String()
+			if jsonIdent.MatchString(key) {
+				s.WriteString("." + key)
+			} else {
+				fmt.Fprintf(s, "[%q]", key)
+			}
+		case cmp.SliceIndex:
+			ix, iy := step.SplitKeys()
+			if ix < 0 {
+				ix = iy
+			}
+			fmt.Fprintf(s, "[%d]", ix)
+		}
+	}
+	return s.String()
+}
+
+// jsonText returns JSON representation of value.
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
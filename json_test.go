@@ -0,0 +1,124 @@
+package sqltest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_compareJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		rs      []string
+		want    string
+		wantErr string
+	}{
+		{name: "equal", rs: []string{`{"b": [1, 2], "a": null}`}, want: `{"a": null, "b": [1, 2.0]}`},
+		{name: "rows", rs: []string{`{"a": 1}`, `{"a": 2}`}, want: `[{"a": 1}, {"a": 2}]`},
+		{
+			name:    "value",
+			rs:      []string{`{"items": [{"price": 1}, {"price": 2}, {"price": 10}]}`},
+			want:    `{"items": [{"price": 1}, {"price": 2}, {"price": 12}]}`,
+			wantErr: "$.items[2].price: 10, want 12",
+		},
+		{
+			name:    "missing-key",
+			rs:      []string{`{"a": 1}`},
+			want:    `{"a": 1, "first name": "x"}`,
+			wantErr: `$["first name"]: missing, want "x"`,
+		},
+		{
+			name:    "unexpected-element",
+			rs:      []string{`[1, 2, 3]`},
+			want:    `[1, 2]`,
+			wantErr: "$[2]: unexpected 3",
+		},
+		{
+			name:    "big-integer",
+			rs:      []string{`{"id": 9007199254740993}`},
+			want:    `{"id": 9007199254740992}`,
+			wantErr: "$.id: 9007199254740993, want 9007199254740992",
+		},
+		{name: "exact-numbers", rs: []string{`[1, 0.10, 100]`}, want: `[1.0, 0.1, 1e2]`},
+		{name: "trailing", rs: []string{`{"a": 1}`}, want: `{"a": 1} x`, wantErr: errAssertJSON.Error()},
+		{name: "type", rs: []string{`"1"`}, want: `1`, wantErr: `$: "1", want 1`},
+		{name: "not-json", rs: []string{`map[a:1]`}, want: `{"a": 1}`, wantErr: errQueryJSON.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := compareJSON(tt.rs, tt.want)
+			if gotErr != nil {
+				if tt.wantErr == "" || !strings.Contains(gotErr.Error(), tt.wantErr) {
+					t.Errorf("compareJSON() failed: %v, want %q", gotErr, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("compareJSON() succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func Test_define_Parse_json(t *testing.T) {
+	ctx, err := parseDefine(context.Background(), "define doc(id)\nSELECT doc FROM docs WHERE id = :id")
+	if err != nil {
+		t.Fatalf("parseDefine() failed: %v", err)
+	}
+	tests := []struct {
+		name      string
+		src       string
+		wantWant  string
+		wantBlock bool
+		wantErr   bool
+	}{
+		{name: "line", src: `assert_json doc(1) {"a": [1, 2]}`, wantWant: `{"a": [1, 2]}`},
+		{name: "block", src: "assert_json doc(1)\n{\n  \"a\": 1\n}", wantWant: "{\n  \"a\": 1\n}", wantBlock: true},
+		{name: "vars", src: `assert_json doc(1) {"a": ${a}}`, wantWant: `{"a": ${a}}`},
+		{name: "malformed", src: `assert_json doc(1) {"a": }`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, que, err := (&define{}).Parse(ctx, []byte(tt.src))
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Parse() succeeded unexpectedly")
+			}
+			a := que.(*assertQuerier)
+			if a.mode != assertJSON || a.want != tt.wantWant || a.block != tt.wantBlock {
+				t.Errorf("Parse() = mode %d, want %q, block %v; want %q, block %v", a.mode, a.want, a.block, tt.wantWant, tt.wantBlock)
+			}
+		})
+	}
+}
+
+func TestTest_Run_updateJSON(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.sql")
+	src := "define doc\nSELECT doc;\nassert_json doc {};\nassert_json doc\n{};\n"
+	if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	test, err := New(strings.NewReader(src), WithFilename(name), WithUpdate(true))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{rows: map[string]fakeRows{"SELECT doc": {values: []string{`[{"b": 1, "a": [2]}]`}}}}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "define doc\nSELECT doc;\nassert_json doc {\"a\":[2],\"b\":1};\nassert_json doc\n{\n  \"a\": [\n    2\n  ],\n  \"b\": 1\n};\n"
+	if string(got) != want {
+		t.Errorf("Run() rewrites file to %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const markerPrefix = "sqltest:"
+
+var (
+	errMarkerUnknown = errors.New("unknown sqltest marker")
+	errTodoPasses    = errors.New("marked as todo, but passes; remove the marker")
+)
+
+// ErrSkip is matched with [errors.Is] by errors returned from Run for skipped tests.
+var ErrSkip = errors.New("test skipped")
+
+// SkipError is returned by Run for tests which are skipped by markers,
+// and for failing tests marked as todo.
+type SkipError struct {
+	Reason string
+	// Todo is true, if test is marked as todo and fails.
+	Todo bool
+}
+
+func (e *SkipError) Error() string {
+	if e.Todo {
+		return "todo: " + e.Reason
+	}
+	return "skip: " + e.Reason
+}
+
+// Is implements errors.Is for ErrSkip.
+func (e *SkipError) Is(target error) bool {
+	return target == ErrSkip
+}
+
+// markers are set by -- sqltest:NAME comments before statements.
+//
+// Markers before the first statement of file apply to the whole test.
+type markers struct {
+	skip   bool // -- sqltest:skip [reason]
+	only   bool // -- sqltest:only
+	todo   bool // -- sqltest:todo [reason]
+	reason string
+	// -- sqltest:max-duration DURATION
+	maxDuration time.Duration
+}
+
+// parseMarkers parses markers from line comments of src and merges them into m.
+func parseMarkers(src []byte, m *markers) error {
+	for line := range bytes.Lines(src) {
+		line = bytes.TrimLeft(line, " \t")
+		text, ok := bytes.CutPrefix(line, []byte("--"))
+		if !ok {
+			continue
+		}
+		text, ok = bytes.CutPrefix(bytes.TrimLeft(text, " \t"), []byte(markerPrefix))
+		if !ok {
+			continue
+		}
+		name, arg, _ := strings.Cut(strings.TrimRight(string(text), " \t\r\n"), " ")
+		arg = strings.Trim(arg, " \t")
+		switch name {
+		case "skip":
+			m.skip = true
+		case "only":
+			m.only = true
+		case "todo":
+			m.todo = true
+		case "max-duration":
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("%w: %q", errMarkerDuration, arg)
+			}
+			m.maxDuration = d
+			continue
+		default:
+			return fmt.Errorf("%w: %s", errMarkerUnknown, name)
+		}
+		if arg != "" {
+			m.reason = arg
+		}
+	}
+	return nil
+}
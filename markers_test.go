@@ -0,0 +1,142 @@
+package sqltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    markers
+		wantErr bool
+	}{
+		{name: "empty", src: ""},
+		{name: "plain-comment", src: "-- skip this\n/* sqltest:skip */"},
+		{name: "skip", src: "-- sqltest:skip flaky on CI\n", want: markers{skip: true, reason: "flaky on CI"}},
+		{name: "only", src: "  --sqltest:only", want: markers{only: true}},
+		{name: "todo", src: "-- comment\n-- sqltest:todo\n", want: markers{todo: true}},
+		{name: "max-duration", src: "-- sqltest:max-duration 50ms\n", want: markers{maxDuration: 50 * time.Millisecond}},
+		{name: "max-duration-malformed", src: "-- sqltest:max-duration fast\n", wantErr: true},
+		{name: "unknown", src: "-- sqltest:skipp", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got markers
+			gotErr := parseMarkers([]byte(tt.src), &got)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseMarkers() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseMarkers() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("parseMarkers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTest_Run_markers(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantExecs []string
+		wantSkip  bool
+		wantErr   bool
+	}{
+		{
+			name:     "file-skip",
+			src:      "-- sqltest:skip not ready\n\nSELECT 1;\nSELECT 2",
+			wantSkip: true,
+		},
+		{
+			name:     "file-skip-include",
+			src:      "-- sqltest:skip\ninclude testdata/include/fixtures/users.sql;\nSELECT 1",
+			wantSkip: true,
+		},
+		{
+			name:      "statement-skip",
+			src:       "SELECT 1;\n-- sqltest:skip\nSELECT 2;\nSELECT 3",
+			wantExecs: []string{"SELECT 1", "SELECT 3"},
+		},
+		{
+			name:      "statement-only",
+			src:       "SELECT 1;\n-- sqltest:only\nSELECT 2;\nSELECT 3",
+			wantExecs: []string{"-- sqltest:only\nSELECT 2"},
+		},
+		{
+			name:      "file-todo-fails",
+			src:       "-- sqltest:todo\n\nSELECT 1;\nFAIL;\nSELECT 2",
+			wantExecs: []string{"-- sqltest:todo\n\nSELECT 1", "FAIL"},
+			wantSkip:  true,
+		},
+		{
+			name:      "file-todo-passes",
+			src:       "-- sqltest:todo\nSELECT 1",
+			wantExecs: []string{"-- sqltest:todo\nSELECT 1"},
+			wantErr:   true,
+		},
+		{
+			name:      "statement-todo-fails",
+			src:       "SELECT 1;\n-- sqltest:todo\nFAIL;\nSELECT 2",
+			wantExecs: []string{"SELECT 1", "-- sqltest:todo\nFAIL", "SELECT 2"},
+			wantSkip:  true,
+		},
+		{
+			name:      "statement-todo-passes",
+			src:       "SELECT 1;\n-- sqltest:todo\nSELECT 2;\nSELECT 3",
+			wantExecs: []string{"SELECT 1", "-- sqltest:todo\nSELECT 2"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			tx := &fakeTx{errs: map[string]error{
+				"FAIL":                  errors.New("fail"),
+				"-- sqltest:todo\nFAIL": errors.New("fail"),
+			}}
+			gotErr := test.Run(tx)
+			if g := errors.Is(gotErr, ErrSkip); g != tt.wantSkip {
+				t.Errorf("Run() = %v, skipped %v, want %v", gotErr, g, tt.wantSkip)
+			}
+			if g := gotErr != nil && !errors.Is(gotErr, ErrSkip); g != tt.wantErr {
+				t.Errorf("Run() = %v, failed %v, want %v", gotErr, g, tt.wantErr)
+			}
+			if g, w := strings.Join(tx.execs, "; "), strings.Join(tt.wantExecs, "; "); g != w {
+				t.Errorf("Run() executes %q, want %q", g, w)
+			}
+		})
+	}
+}
+
+func TestNewSet_only(t *testing.T) {
+	set, err := NewSet(iter.Seq2[string, io.Reader](func(yield func(string, io.Reader) bool) {
+		_ = yield("a", strings.NewReader("SELECT 1")) &&
+			yield("b", strings.NewReader("-- sqltest:only\nSELECT 2"))
+	}))
+	if err != nil {
+		t.Fatalf("NewSet() failed: %v", err)
+	}
+	var got []string
+	for name, test := range set.All() {
+		err := test.Run(&fakeTx{})
+		got = append(got, fmt.Sprintf("%s: %v", name, errors.Is(err, ErrSkip)))
+	}
+	if g, w := strings.Join(got, ", "), "a: true, b: false"; g != w && g != "b: false, a: true" {
+		t.Errorf("NewSet() skipped = %q, want %q", g, w)
+	}
+}
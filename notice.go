@@ -0,0 +1,104 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const noticeKey = "expect_notice "
+
+var (
+	errNoticeWoText      = errors.New("missing text in expect_notice statement")
+	errNoticeWoQuery     = errors.New("missing query in expect_notice statement")
+	errNoticeUnsupported = errors.New("expect_notice requires Tx implementing NoticeTx")
+	errNoticeMissing     = errors.New("statement does not raise notice")
+	errNoticeUnchecked   = errors.New("statement raises unchecked warning")
+)
+
+// Notice is a message sent by database during statement, like RAISE NOTICE of PostgreSQL.
+type Notice struct {
+	// Severity of notice, like NOTICE or WARNING.
+	Severity string
+	Message  string
+}
+
+func (n Notice) String() string {
+	return n.Severity + ": " + n.Message
+}
+
+// NoticeTx is optionally implemented by [Tx] to expose notices collected by driver.
+//
+// If Tx implements it, statements are checked by preceding expect_notice lines.
+type NoticeTx interface {
+	Tx
+
+	// Notices returns notices collected since the previous call and forgets them.
+	Notices() []Notice
+}
+
+// Fail statements raising warnings, which are not expected by expect_notice lines.
+func WithStrictNotices() Option {
+	return func(pc *parseConfig) {
+		pc.strictNotices = true
+	}
+}
+
+// parseNotice cuts expect_notice line preceding statement.
+// It returns length of the cut line and expected text of notice.
+func parseNotice(src []byte) (int, string, bool, error) {
+	if !bytes.HasPrefix(src, []byte(noticeKey)) {
+		return 0, "", false, nil
+	}
+	line, _, found := bytes.Cut(src[len(noticeKey):], []byte("\n"))
+	text := strings.Trim(string(line), " \t\r")
+	if text == "" {
+		return 0, "", true, errNoticeWoText
+	}
+	n := len(noticeKey) + len(line) + 1
+	if !found || len(bytes.TrimSpace(src[n:])) == 0 {
+		return 0, "", true, errNoticeWoQuery
+	}
+	return n, text, true, nil
+}
+
+// drainNotices forgets notices raised before query.
+func drainNotices(tx Tx) {
+	if ntx, ok := tx.(NoticeTx); ok {
+		ntx.Notices()
+	}
+}
+
+// checkNotices checks notices raised by query q.
+func (test *Test) checkNotices(tx Tx, q query) error {
+	ntx, ok := tx.(NoticeTx)
+	if !ok {
+		if q.notices != nil {
+			return errNoticeUnsupported
+		}
+		return nil
+	}
+	notices := ntx.Notices()
+	checked := make([]bool, len(notices))
+	for _, want := range q.notices {
+		found := false
+		for i, n := range notices {
+			if strings.Contains(n.Message, want) {
+				checked[i], found = true, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w %q; notices: %v", errNoticeMissing, want, notices)
+		}
+	}
+	if !test.strictNotices {
+		return nil
+	}
+	for i, n := range notices {
+		if !checked[i] && strings.EqualFold(n.Severity, "WARNING") {
+			return fmt.Errorf("%w: %s", errNoticeUnchecked, n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sqltest
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// noticeTx raises prepared notices for statements.
+type noticeTx struct {
+	fakeTx
+	raises  map[string][]Notice
+	notices []Notice
+}
+
+func (tx *noticeTx) Exec(ctx context.Context, sql string, args ...any) error {
+	tx.notices = append(tx.notices, tx.raises[sql]...)
+	return tx.fakeTx.Exec(ctx, sql, args...)
+}
+
+func (tx *noticeTx) Notices() []Notice {
+	n := tx.notices
+	tx.notices = nil
+	return n
+}
+
+var _ NoticeTx = (*noticeTx)(nil)
+
+func Test_parseNotice(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantN    int
+		wantText string
+		wantOk   bool
+		wantErr  bool
+	}{
+		{name: "other", src: "SELECT 1"},
+		{name: "ok", src: "expect_notice salary changed \nUPDATE 1", wantN: 30, wantText: "salary changed", wantOk: true},
+		{name: "missing-text", src: "expect_notice \nUPDATE 1", wantOk: true, wantErr: true},
+		{name: "missing-query", src: "expect_notice salary changed", wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotN, gotText, gotOk, gotErr := parseNotice([]byte(tt.src))
+			if gotOk != tt.wantOk {
+				t.Errorf("parseNotice() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseNotice() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseNotice() succeeded unexpectedly")
+			}
+			if gotN != tt.wantN || gotText != tt.wantText {
+				t.Errorf("parseNotice() = %d, %q, want %d, %q", gotN, gotText, tt.wantN, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestTest_Run_notices(t *testing.T) {
+	raises := map[string][]Notice{
+		"UPDATE 1": {{Severity: "NOTICE", Message: "salary changed from 1 to 2"}},
+		"UPDATE 2": {{Severity: "WARNING", Message: "salary decreased"}},
+		"UPDATE 3": {{Severity: "NOTICE", Message: "salary changed"}, {Severity: "WARNING", Message: "salary decreased"}},
+	}
+	tests := []struct {
+		name    string
+		src     string
+		opts    []Option
+		plain   bool
+		session bool
+		wantErr string
+	}{
+		{name: "expected", src: "SELECT 1;\nexpect_notice salary changed\nUPDATE 1"},
+		{name: "missing", src: "expect_notice salary changed\nUPDATE 2", wantErr: errNoticeMissing.Error()},
+		{name: "previous", src: "UPDATE 1;\nexpect_notice salary changed\nSELECT 1", wantErr: errNoticeMissing.Error()},
+		{name: "several", src: "expect_notice changed\nexpect_notice decreased\nUPDATE 3"},
+		{name: "unchecked", src: "UPDATE 2;\nexpect_notice changed\nUPDATE 3"},
+		{name: "strict", src: "UPDATE 1;\nUPDATE 2", opts: []Option{WithStrictNotices()}, wantErr: errNoticeUnchecked.Error()},
+		{
+			name: "strict-checked",
+			src:  "UPDATE 1;\nexpect_notice changed\nexpect_notice decreased\nUPDATE 3",
+			opts: []Option{WithStrictNotices()},
+		},
+		{name: "unsupported", src: "expect_notice changed\nUPDATE 1", plain: true, wantErr: errNoticeUnsupported.Error()},
+		{name: "async", src: "session A;\nexpect_notice changed\nasync\nUPDATE 1;\nawait", session: true},
+		{
+			name:    "async-missing",
+			src:     "session A;\nexpect_notice changed\nasync\nUPDATE 2;\nawait",
+			session: true,
+			wantErr: errNoticeMissing.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src), tt.opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			var tx Tx = &noticeTx{raises: raises}
+			if tt.plain {
+				tx = &fakeTx{}
+			}
+			var gotErr error
+			if tt.session {
+				gotErr = test.RunSessions(func(string) (Tx, error) { return tx, nil })
+			} else {
+				gotErr = test.Run(tx)
+			}
+			if tt.wantErr == "" && gotErr != nil || tt.wantErr != "" && (gotErr == nil || !strings.Contains(gotErr.Error(), tt.wantErr)) {
+				t.Errorf("Run() error = %v, want %q", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package sqltest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	planKey = "assert_plan "
+	planLen = len(planKey)
+)
+
+var (
+	errPlanWoCheck = errors.New("assert_plan expects either checks on the same line or plan on the following lines")
+	errPlanCheck   = errors.New("malformed check in assert_plan statement, it should be uses or without node type or index name")
+)
+
+// PlanDialect explains queries and inspects their plans for assert_plan statements.
+type PlanDialect interface {
+	// Explain returns statement which returns plan of query.
+	Explain(query string) string
+	// Nodes parses plan returned by explain statement into nodes in depth-first order.
+	Nodes(plan string) ([]PlanNode, error)
+}
+
+// PlanNode is a node of query plan.
+type PlanNode struct {
+	// Type of node, like Seq Scan or Index Scan.
+	Type string
+	// Index used by node, if any.
+	Index string
+	// Relation scanned by node, if any.
+	Relation string
+	// Depth of node in plan tree starting from 0.
+	Depth int
+}
+
+// String returns node as line of plan snapshot.
+func (n PlanNode) String() string {
+	s := strings.Repeat("  ", n.Depth) + n.Type
+	if n.Index != "" {
+		s += " using " + n.Index
+	}
+	if n.Relation != "" {
+		s += " on " + n.Relation
+	}
+	return s
+}
+
+// PostgresPlans explains queries with EXPLAIN (FORMAT JSON) of PostgreSQL.
+var PostgresPlans PlanDialect = postgresPlans{}
+
+type postgresPlans struct{}
+
+func (postgresPlans) Explain(query string) string { return "EXPLAIN (FORMAT JSON) " + query }
+
+func (postgresPlans) Nodes(plan string) ([]PlanNode, error) {
+	type node struct {
+		Type     string `json:"Node Type"`
+		Index    string `json:"Index Name"`
+		Relation string `json:"Relation Name"`
+		Plans    []node `json:"Plans"`
+	}
+	var root []struct {
+		Plan node `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &root); err != nil {
+		return nil, fmt.Errorf("malformed plan: %v", err)
+	}
+	var nodes []PlanNode
+	var walk func(n node, depth int)
+	walk = func(n node, depth int) {
+		nodes = append(nodes, PlanNode{Type: n.Type, Index: n.Index, Relation: n.Relation, Depth: depth})
+		for _, c := range n.Plans {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range root {
+		walk(r.Plan, 0)
+	}
+	return nodes, nil
+}
+
+// Set dialect of assert_plan statements. By default [PostgresPlans] is used.
+func WithPlanDialect(dialect PlanDialect) Option {
+	return func(pc *parseConfig) {
+		pc.plans = dialect
+	}
+}
+
+type plan struct {
+	dialect PlanDialect
+}
+
+// Parse implements QueryParser.
+func (p *plan) Parse(ctx context.Context, src []byte) (context.Context, Querier, error) {
+	source := string(src)
+	if !strings.HasPrefix(source, planKey) {
+		return nil, nil, nil
+	}
+	head, body, _ := strings.Cut(source[planLen:], "\n")
+	key, args, rest, err := parseCall(head)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := lookupDefine(ctx, key, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := &planQuerier{query: d.query, args: args, dialect: p.dialect}
+	trimmed := strings.TrimLeft(body, " \t\r\n")
+	switch {
+	case rest != "" && trimmed != "", rest == "" && trimmed == "":
+		return nil, nil, errPlanWoCheck
+	case rest != "":
+		for _, c := range strings.Split(rest, ",") {
+			check, err := parsePlanCheck(c)
+			if err != nil {
+				return nil, nil, err
+			}
+			q.checks = append(q.checks, check)
+		}
+	default:
+		// Snapshot starts from the beginning of its first line.
+		start := strings.LastIndexByte(body[:len(body)-len(trimmed)], '\n') + 1
+		body = strings.TrimRight(body[start:], " \t\r\n")
+		q.span[0] = planLen + len(head) + 1 + start
+		q.span[1] = q.span[0] + len(body)
+		for _, line := range strings.Split(body, "\n") {
+			q.snapshot = append(q.snapshot, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return nil, q, nil
+}
+
+// Directives implements DirectiveParser.
+func (p *plan) Directives() (header, data []string) {
+	return nil, []string{strings.TrimSpace(planKey)}
+}
+
+var _ DirectiveParser = (*plan)(nil)
+
+// planCheck is a condition on plan nodes.
+type planCheck struct {
+	without bool
+	index   bool
+	value   string
+}
+
+// parsePlanCheck parses check like uses index NAME, or without NODE TYPE.
+func parsePlanCheck(src string) (planCheck, error) {
+	var c planCheck
+	src = strings.TrimSpace(src)
+	verb, rest, _ := strings.Cut(src, " ")
+	switch verb {
+	case "uses":
+	case "without":
+		c.without = true
+	default:
+		return c, fmt.Errorf("%w: %q", errPlanCheck, src)
+	}
+	rest = strings.TrimSpace(rest)
+	if name, ok := strings.CutPrefix(rest+" ", "index "); ok {
+		c.index = true
+		rest = strings.TrimSpace(name)
+	}
+	if rest == "" {
+		return c, fmt.Errorf("%w: %q", errPlanCheck, src)
+	}
+	c.value = rest
+	return c, nil
+}
+
+// String returns check as it is written.
+func (c planCheck) String() string {
+	s := "uses "
+	if c.without {
+		s = "without "
+	}
+	if c.index {
+		s += "index "
+	}
+	return s + c.value
+}
+
+// match reports whether check is satisfied by nodes.
+func (c planCheck) match(nodes []PlanNode) bool {
+	found := false
+	for _, n := range nodes {
+		if c.index && n.Index == c.value || !c.index && n.Type == c.value {
+			found = true
+			break
+		}
+	}
+	return found != c.without
+}
+
+type planQuerier struct {
+	query   string
+	args    []any
+	dialect PlanDialect
+	checks  []planCheck
+	// Expected plan, when it is written as a block of lines.
+	snapshot []string
+	// Byte offsets of snapshot in parsed source, which are rewritten in update mode.
+	span [2]int
+}
+
+// Query implements Querier.
+func (p *planQuerier) Query(ctx context.Context, tx Tx) error {
+	sql, err := expandVars(ctx, p.query)
+	if err != nil {
+		return err
+	}
+	args, err := expandArgs(ctx, p.args)
+	if err != nil {
+		return err
+	}
+	rs, err := queryTexts(ctx, tx, p.dialect.Explain(sql), args...)
+	if err != nil {
+		return err
+	}
+	nodes, err := p.dialect.Nodes(strings.Join(rs, "\n"))
+	if err != nil {
+		return err
+	}
+	got := make([]string, len(nodes))
+	for i, n := range nodes {
+		got[i] = n.String()
+	}
+	if u := updateFrom(ctx); u != nil && p.snapshot != nil {
+		u.record(p.span, strings.Join(got, "\n"))
+		return nil
+	}
+	for _, c := range p.checks {
+		if !c.match(nodes) {
+			return fmt.Errorf("plan of defined query does not satisfy %q:\n%s", c, strings.Join(got, "\n"))
+		}
+	}
+	if p.snapshot != nil && strings.Join(got, "\n") != strings.Join(p.snapshot, "\n") {
+		return fmt.Errorf("plan of defined query is\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(p.snapshot, "\n"))
+	}
+	return nil
+}
+
+var _ Querier = (*planQuerier)(nil)
@@ -0,0 +1,118 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_plan_Parse(t *testing.T) {
+	ctx, err := parseDefine(context.Background(), "define last_log(id)\nSELECT * FROM emp_log WHERE id = :id")
+	if err != nil {
+		t.Fatalf("parseDefine() failed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{name: "other", src: "assert last_log(1) [1]", want: "<nil>"},
+		{
+			name: "checks",
+			src:  "assert_plan last_log(1) uses index emp_log_pkey, without Seq Scan",
+			want: `$1 [1] [uses index emp_log_pkey without Seq Scan] [] [0 0]`,
+		},
+		{
+			name: "snapshot",
+			src:  "assert_plan last_log(1)\nLimit\n  Index Scan using emp_log_pkey on emp_log\n",
+			want: `$1 [1] [] [Limit   Index Scan using emp_log_pkey on emp_log] [24 72]`,
+		},
+		{name: "missing-checks", src: "assert_plan last_log(1)", wantErr: true},
+		{name: "checks-and-snapshot", src: "assert_plan last_log(1) uses Limit\nLimit", wantErr: true},
+		{name: "malformed-check", src: "assert_plan last_log(1) has index emp_log_pkey", wantErr: true},
+		{name: "empty-check", src: "assert_plan last_log(1) uses index", wantErr: true},
+		{name: "undefined", src: "assert_plan first_log uses Limit", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gotQue, gotErr := (&plan{dialect: PostgresPlans}).Parse(ctx, []byte(tt.src))
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Parse() succeeded unexpectedly")
+			}
+			got := "<nil>"
+			if p, _ := gotQue.(*planQuerier); p != nil {
+				got = fmt.Sprintf("%s %v %v %q %v", strings.Fields(p.query)[7], p.args, p.checks, p.snapshot, p.span)
+				got = strings.ReplaceAll(got, `"`, "")
+			}
+			if got != tt.want {
+				t.Errorf("Parse() querier = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_postgresPlans_Nodes(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Limit", "Plans": [
+		{"Node Type": "Index Scan", "Index Name": "emp_log_pkey", "Relation Name": "emp_log"}
+	]}}]`
+	nodes, err := PostgresPlans.Nodes(plan)
+	if err != nil {
+		t.Fatalf("Nodes() failed: %v", err)
+	}
+	var got []string
+	for _, n := range nodes {
+		got = append(got, n.String())
+	}
+	if g, w := strings.Join(got, "\n"), "Limit\n  Index Scan using emp_log_pkey on emp_log"; g != w {
+		t.Errorf("Nodes() = %q, want %q", g, w)
+	}
+	if _, err := PostgresPlans.Nodes("Seq Scan on emp"); err == nil {
+		t.Error("Nodes() of text plan succeeded unexpectedly")
+	}
+}
+
+func Test_planQuerier_Query(t *testing.T) {
+	tx := &fakeTx{rows: map[string]fakeRows{
+		"EXPLAIN (FORMAT JSON) index": {values: []string{
+			`[[{"Plan": {"Node Type": "Index Scan", "Index Name": "emp_pkey", "Relation Name": "emp"}}]]`,
+		}},
+		"EXPLAIN (FORMAT JSON) seq": {values: []string{
+			`[[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "emp"}}]]`,
+		}},
+	}}
+	tests := []struct {
+		name    string
+		querier planQuerier
+		wantErr bool
+	}{
+		{name: "uses-index", querier: planQuerier{query: "index", checks: []planCheck{{index: true, value: "emp_pkey"}}}},
+		{name: "uses-index-differ", querier: planQuerier{query: "seq", checks: []planCheck{{index: true, value: "emp_pkey"}}}, wantErr: true},
+		{name: "without-node", querier: planQuerier{query: "index", checks: []planCheck{{without: true, value: "Seq Scan"}}}},
+		{name: "without-node-differ", querier: planQuerier{query: "seq", checks: []planCheck{{without: true, value: "Seq Scan"}}}, wantErr: true},
+		{name: "snapshot", querier: planQuerier{query: "seq", snapshot: []string{"Seq Scan on emp"}}},
+		{name: "snapshot-differ", querier: planQuerier{query: "index", snapshot: []string{"Seq Scan on emp"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.querier.dialect = PostgresPlans
+			gotErr := tt.querier.Query(context.Background(), tx)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Query() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Query() succeeded unexpectedly")
+			}
+		})
+	}
+}
@@ -18,43 +18,207 @@ func pos(index int) position {
 	return position{index: index}
 }
 
+// QueryDelimiter returns position of the delimiter which ends the first query in source.
+// If source has no delimiter, position points right after the end of source.
 type QueryDelimiter func([]byte) position
 
-func defaultQueryDelimiter(src []byte) position {
+// delimiter is default [QueryDelimiter], which splits queries by ; at the end of line.
+//
+// Delimiters inside string literals, quoted identifiers, dollar quoted strings
+// and comments are ignored, so function bodies may be written as is.
+// Lines of directives d are not SQL, so quotes are not tracked in them.
+func (d directives) delimiter(src []byte) position {
 	pos := pos(len(src)) // by default index is right after the end
-	var off int
-	for {
-		nl := bytes.IndexByte(src, '\n')
-		if nl < 0 {
-			if i := delimeterBackward(src, len(src)-1); i >= 0 {
-				pos.index = off + i
+	var line int
+	// Leading lines of statement may be sqltest directives, which are not SQL.
+	header := true
+	for i := 0; i < len(src); i++ {
+		if header && (i == 0 || src[i-1] == '\n') {
+			start := i + len(src[i:]) - len(bytes.TrimLeft(src[i:], " \t"))
+			switch d.line(src[start:]) {
+			case directiveData:
+				return dataDelimiter(src, i, line)
+			case directiveHeader:
+				end := len(src)
+				if nl := bytes.IndexByte(src[i:], '\n'); nl >= 0 {
+					end = i + nl
+				}
+				for j := i; j < end; j++ {
+					if src[j] == ';' && delimiterForward(src, j+1) {
+						return position{line: line, index: j}
+					}
+				}
+				i = end - 1 // newline is counted on next iteration
+				continue
+			case directiveNone:
+				header = bytes.HasPrefix(src[start:], []byte("--"))
 			}
-			return pos
-		}
-		if i := delimeterBackward(src, nl-1); i >= 0 {
-			pos.index = off + i
-			return pos
 		}
-		pos.line++
-		off += nl + 1
-		if pos.index <= off {
-			return pos
+		switch c := src[i]; c {
+		case '\n':
+			line++
+		case ';':
+			if delimiterForward(src, i+1) {
+				pos.index = i
+				pos.line = line
+				return pos
+			}
+		case '\'':
+			escapes := i > 0 && (src[i-1] == 'E' || src[i-1] == 'e') && (i == 1 || !isIdentChar(src[i-2]))
+			i, line = skipQuoted(src, i+1, line, '\'', escapes)
+		case '"':
+			i, line = skipQuoted(src, i+1, line, '"', false)
+		case '$':
+			if i > 0 && isIdentChar(src[i-1]) {
+				continue
+			}
+			if tag := dollarTag(src[i:]); tag != nil {
+				i, line = skipDollarQuoted(src, i+len(tag), line, tag)
+			}
+		case '-':
+			if i+1 < len(src) && src[i+1] == '-' {
+				nl := bytes.IndexByte(src[i:], '\n')
+				if nl < 0 {
+					i = len(src)
+				} else {
+					i += nl - 1 // newline is counted on next iteration
+				}
+			}
+		case '/':
+			if i+1 < len(src) && src[i+1] == '*' {
+				i, line = skipBlockComment(src, i+2, line)
+			}
 		}
-		src = src[nl+1:]
 	}
+	pos.line = line
+	return pos
 }
 
-func delimeterBackward(src []byte, i int) int {
-	for ; i >= 0; i-- {
+// Kinds of statement lines for delimiter.
+const (
+	directiveNone   = iota // SQL or comment
+	directiveHeader        // directive line followed by SQL
+	directiveData          // directive which following lines are expected values
+)
+
+// directives are keys of directives with their kinds.
+type directives map[string]int
+
+// line returns kind of line by its directive key.
+func (d directives) line(line []byte) int {
+	end := bytes.IndexAny(line, " \t\r\n")
+	if end < 0 {
+		end = len(line)
+	}
+	return d[string(line[:end])]
+}
+
+// dataDelimiter splits statement with expected values at ; in the end of line from i,
+// without tracking of SQL quotes.
+func dataDelimiter(src []byte, i, line int) position {
+	for ; i < len(src); i++ {
 		switch src[i] {
-		case ' ', '\t':
+		case '\n':
+			line++
 		case ';':
-			return i
+			if delimiterForward(src, i+1) {
+				return position{line: line, index: i}
+			}
+		}
+	}
+	return position{line: line, index: len(src)}
+}
+
+// delimiterForward reports whether src from i up to the end of line contains only whitespaces.
+func delimiterForward(src []byte, i int) bool {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			return true
 		default:
-			return -1
+			return false
+		}
+	}
+	return true
+}
+
+// skipQuoted returns index of closing quote character and updated line counter.
+// Doubled quote characters are handled as two adjacent literals.
+func skipQuoted(src []byte, i, line int, quote byte, escapes bool) (int, int) {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case quote:
+			return i, line
+		case '\n':
+			line++
+		case '\\':
+			if escapes && i+1 < len(src) {
+				i++
+				if src[i] == '\n' {
+					line++
+				}
+			}
+		}
+	}
+	return i, line
+}
+
+// dollarTag returns tag of dollar quoted string ($$ or $tag$) at the start of src, or nil.
+func dollarTag(src []byte) []byte {
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '$':
+			return src[:i+1]
+		case c >= '0' && c <= '9':
+			if i == 1 {
+				return nil
+			}
+		case !isIdentChar(c):
+			return nil
 		}
 	}
-	return -1
+	return nil
+}
+
+// skipDollarQuoted returns index of the last byte of closing tag and updated line counter.
+func skipDollarQuoted(src []byte, i, line int, tag []byte) (int, int) {
+	end := bytes.Index(src[i:], tag)
+	if end < 0 {
+		return len(src), line + bytes.Count(src[i:], []byte{'\n'})
+	}
+	return i + end + len(tag) - 1, line + bytes.Count(src[i:i+end], []byte{'\n'})
+}
+
+// skipBlockComment returns index of the last byte of comment and updated line counter.
+// Block comments may be nested.
+func skipBlockComment(src []byte, i, line int) (int, int) {
+	depth := 1
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '\n':
+			line++
+		case '/':
+			if i+1 < len(src) && src[i+1] == '*' {
+				depth++
+				i++
+			}
+		case '*':
+			if i+1 < len(src) && src[i+1] == '/' {
+				depth--
+				i++
+				if depth == 0 {
+					return i, line
+				}
+			}
+		}
+	}
+	return i, line
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
 }
 
 func skipEmptyLines(src []byte) position {
@@ -134,6 +298,7 @@ func skipCommentaries(src []byte) position {
 				if com == commentLine {
 					com = commentNone
 				}
+			case ' ', '\t', '\r':
 			default:
 				if com == commentNone {
 					pos.index = i
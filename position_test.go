@@ -1,8 +1,14 @@
 package sqltest
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
 
-func Test_defaultQueryDelimiter(t *testing.T) {
+func Test_directives_delimiter(t *testing.T) {
+	delimiter := newParserConfig().delimiter
 	for _, tt := range []struct {
 		name string
 		src  string
@@ -25,11 +31,29 @@ func Test_defaultQueryDelimiter(t *testing.T) {
 		{name: "alpha-nl-alpha-delim", src: "a\nb;", want: position{index: 3, line: 1}},
 		{name: "alpha-nl-alpha-delim-nl-alpha", src: "a\nb;\nc", want: position{index: 3, line: 1}},
 		{name: "fix-offset-overlap", src: "a\nb\nc", want: position{line: 2, index: 5}},
+		{name: "string-literal", src: "'a;\nb';\nc", want: position{line: 1, index: 6}},
+		{name: "string-literal-doubled-quote", src: "'a'';\n';", want: position{line: 1, index: 7}},
+		{name: "escape-string", src: "E'\\';\n';", want: position{line: 1, index: 7}},
+		{name: "escape-string-ident", src: "ne'\\';\n", want: position{line: 0, index: 5}},
+		{name: "quoted-ident", src: "\"a;\n\";", want: position{line: 1, index: 5}},
+		{name: "dollar-quote", src: "$$a;\n$$;\nb", want: position{line: 1, index: 7}},
+		{name: "dollar-quote-tag", src: "$fn$a;\n$$;\n$fn$;", want: position{line: 2, index: 15}},
+		{name: "dollar-param", src: "$1;\n$2", want: position{line: 0, index: 2}},
+		{name: "dollar-in-ident", src: "a$b$;\nc", want: position{line: 0, index: 4}},
+		{name: "line-comment", src: "a -- b;\nc;", want: position{line: 1, index: 9}},
+		{name: "block-comment-nested", src: "/* /* */ ;\n */;", want: position{line: 1, index: 14}},
+		{name: "unterminated-literal", src: "'a;\nb", want: position{line: 1, index: 5}},
+		{name: "crlf", src: "a;\r\nb", want: position{index: 1}},
+		{name: "assert-quote", src: "assert names [O'Brien];\nSELECT 1;", want: position{index: 22}},
+		{name: "assert-block-quote", src: "assert names\nO'Brien\nO'Hara;\nb", want: position{line: 2, index: 27}},
+		{name: "except-quote", src: "except can't insert\nINSERT 'a;\n';\nb", want: position{line: 2, index: 32}},
+		{name: "comment-directive-quote", src: "-- it's\nexpect_notice can't\nSELECT 1;\nb", want: position{line: 2, index: 36}},
+		{name: "directive-word", src: "tests 'a;\n';", want: position{line: 1, index: 11}},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			got := defaultQueryDelimiter([]byte(tt.src))
+			got := delimiter([]byte(tt.src))
 			if got != tt.want {
-				t.Errorf("defaultQueryDelimiter() = %#v, want %#v", got, tt.want)
+				t.Errorf("delimiter() = %#v, want %#v", got, tt.want)
 			}
 		})
 	}
@@ -69,6 +93,7 @@ func Test_skipCommentaries(t *testing.T) {
 		{name: "line-comment-full", src: "--skip\ntest", want: position{line: 1, index: 7}},
 		{name: "block-comment", src: "/*test*/test", want: position{line: 0, index: 8}},
 		{name: "block-comment-full", src: "/*sk\nip*/\ntest", want: position{line: 2, index: 10}},
+		{name: "block-comment-ws", src: "/* skip */ test", want: position{line: 0, index: 11}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -79,3 +104,38 @@ func Test_skipCommentaries(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_directiveQuotes(t *testing.T) {
+	src := "define names\nSELECT name FROM emp;\nassert names [O'Brien];\nINSERT INTO emp VALUES ('a;\n');\nassert names\nO'Brien\na;\n"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if len(test.queries) != 3 {
+		t.Errorf("New() parses %d queries, want 3", len(test.queries))
+	}
+}
+
+// checkParser parses custom directive, which line is not SQL.
+type checkParser struct{}
+
+func (p *checkParser) Parse(ctx context.Context, src []byte) (context.Context, Querier, error) {
+	if !bytes.HasPrefix(src, []byte("mycheck ")) {
+		return nil, nil, nil
+	}
+	return nil, &execQuerier{src}, nil
+}
+
+func (p *checkParser) Directives() (header, data []string) {
+	return []string{"mycheck"}, nil
+}
+
+func TestNew_extraDirective(t *testing.T) {
+	test, err := New(strings.NewReader("mycheck it's fine;\nSELECT 1;"), WithExtraParsers(&checkParser{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if len(test.queries) != 2 {
+		t.Errorf("New() parses %d queries, want 2", len(test.queries))
+	}
+}
@@ -0,0 +1,122 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+const (
+	resetKey         = "reset"
+	resetSavepoint   = "sqltest_reset"
+	sectionSavepoint = "sqltest_section"
+)
+
+// isReset reports whether src is reset statement.
+func isReset(src []byte) bool {
+	return string(bytes.TrimRight(src, " \t\r\n")) == resetKey
+}
+
+// Wrap each section of test into savepoint and roll back to it after the section,
+// so sections start from the state left by preamble.
+func WithSectionReset() Option {
+	return func(pc *parseConfig) {
+		pc.sectionReset = true
+	}
+}
+
+// resetter rolls back changes of sections and blocks between reset statements.
+type resetter struct {
+	ss       *sessions
+	dialect  SavepointDialect
+	sections bool
+	// Section which is wrapped into savepoint.
+	section int
+	// Sections in which savepoints of reset statement are created.
+	resets []int
+}
+
+// resetter of test uses transaction of default session.
+func (test *Test) resetter(ss *sessions) *resetter {
+	r := &resetter{ss: ss, dialect: test.savepoints, sections: test.sectionReset}
+	if r.dialect == nil {
+		r.dialect = StandardSavepoints
+	}
+	return r
+}
+
+// exec sql in transaction of default session.
+func (r *resetter) exec(ctx context.Context, sql string) error {
+	tx, err := r.ss.tx("")
+	if err != nil {
+		return err
+	}
+	return tx.Exec(ctx, sql)
+}
+
+// enter is called before query q.
+func (r *resetter) enter(ctx context.Context, q query) error {
+	if !r.sections || q.section == r.section {
+		return nil
+	}
+	if err := r.leave(ctx); err != nil {
+		return err
+	}
+	if q.section == 0 {
+		return nil
+	}
+	if err := r.exec(ctx, r.dialect.Savepoint(sectionSavepoint)); err != nil {
+		return fmt.Errorf("savepoint of section: %v", err)
+	}
+	r.section = q.section
+	return nil
+}
+
+// rollback to state of the first reset statement of section, or remember it.
+func (r *resetter) rollback(ctx context.Context) error {
+	if n := len(r.resets); n == 0 || r.resets[n-1] != r.section {
+		if err := r.exec(ctx, r.dialect.Savepoint(resetSavepoint)); err != nil {
+			return fmt.Errorf("savepoint of reset: %v", err)
+		}
+		r.resets = append(r.resets, r.section)
+		return nil
+	}
+	if err := r.exec(ctx, r.dialect.RollbackTo(resetSavepoint)); err != nil {
+		return fmt.Errorf("rollback to savepoint of reset: %v", err)
+	}
+	return nil
+}
+
+// leave rolls back changes of current section.
+func (r *resetter) leave(ctx context.Context) error {
+	if r.section == 0 {
+		return nil
+	}
+	if err := r.exec(ctx, r.dialect.RollbackTo(sectionSavepoint)); err != nil {
+		return fmt.Errorf("rollback to savepoint of section: %v", err)
+	}
+	if err := r.exec(ctx, r.dialect.Release(sectionSavepoint)); err != nil {
+		return fmt.Errorf("release savepoint of section: %v", err)
+	}
+	// Savepoint of reset inside the section is gone with rollback.
+	if n := len(r.resets); n > 0 && r.resets[n-1] == r.section {
+		r.resets = r.resets[:n-1]
+	}
+	r.section = 0
+	return nil
+}
+
+// close rolls back changes of the last section and after the last reset statement.
+func (r *resetter) close(ctx context.Context) error {
+	if err := r.leave(ctx); err != nil || len(r.resets) == 0 {
+		return err
+	}
+	if err := r.exec(ctx, r.dialect.RollbackTo(resetSavepoint)); err != nil {
+		return fmt.Errorf("rollback to savepoint of reset: %v", err)
+	}
+	if err := r.exec(ctx, r.dialect.Release(resetSavepoint)); err != nil {
+		return fmt.Errorf("release savepoint of reset: %v", err)
+	}
+	r.resets = nil
+	return nil
+}
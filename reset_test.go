@@ -0,0 +1,98 @@
+package sqltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTest_Run_reset(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		src       string
+		wantExecs []string
+	}{
+		{
+			name: "reset",
+			src:  "CREATE 1;\nreset;\nINSERT 1;\nreset;\nINSERT 2",
+			wantExecs: []string{
+				"CREATE 1",
+				"SAVEPOINT sqltest_reset",
+				"INSERT 1",
+				"ROLLBACK TO SAVEPOINT sqltest_reset",
+				"INSERT 2",
+				"ROLLBACK TO SAVEPOINT sqltest_reset",
+				"RELEASE SAVEPOINT sqltest_reset",
+			},
+		},
+		{
+			name:      "sections-disabled",
+			src:       "CREATE 1;\ntest \"a\";\nINSERT 1;\ntest \"b\";\nINSERT 2",
+			wantExecs: []string{"CREATE 1", "INSERT 1", "INSERT 2"},
+		},
+		{
+			name: "sections",
+			opts: []Option{WithSectionReset()},
+			src:  "CREATE 1;\ntest \"a\";\nINSERT 1;\ntest \"b\";\nINSERT 2",
+			wantExecs: []string{
+				"CREATE 1",
+				"SAVEPOINT sqltest_section",
+				"INSERT 1",
+				"ROLLBACK TO SAVEPOINT sqltest_section",
+				"RELEASE SAVEPOINT sqltest_section",
+				"SAVEPOINT sqltest_section",
+				"INSERT 2",
+				"ROLLBACK TO SAVEPOINT sqltest_section",
+				"RELEASE SAVEPOINT sqltest_section",
+			},
+		},
+		{
+			name: "sections-with-reset",
+			opts: []Option{WithSectionReset()},
+			src:  "reset;\ntest \"a\";\nINSERT 1;\nreset;\nINSERT 2",
+			wantExecs: []string{
+				"SAVEPOINT sqltest_reset",
+				"SAVEPOINT sqltest_section",
+				"INSERT 1",
+				"SAVEPOINT sqltest_reset",
+				"INSERT 2",
+				"ROLLBACK TO SAVEPOINT sqltest_section",
+				"RELEASE SAVEPOINT sqltest_section",
+				"ROLLBACK TO SAVEPOINT sqltest_reset",
+				"RELEASE SAVEPOINT sqltest_reset",
+			},
+		},
+		{
+			name: "custom-dialect",
+			opts: []Option{WithSavepoints(testSavepoints{})},
+			src:  "reset;\nINSERT 1",
+			wantExecs: []string{
+				"SP sqltest_reset",
+				"INSERT 1",
+				"RB sqltest_reset",
+				"RL sqltest_reset",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src), tt.opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			tx := &fakeTx{}
+			if err := test.Run(tx); err != nil {
+				t.Errorf("Run() failed: %v", err)
+			}
+			if g, w := strings.Join(tx.execs, "; "), strings.Join(tt.wantExecs, "; "); g != w {
+				t.Errorf("Run() executes %q, want %q", g, w)
+			}
+		})
+	}
+}
+
+type testSavepoints struct{}
+
+func (testSavepoints) Savepoint(name string) string  { return "SP " + name }
+func (testSavepoints) RollbackTo(name string) string { return "RB " + name }
+func (testSavepoints) Release(name string) string    { return "RL " + name }
@@ -0,0 +1,266 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var errTableCells = errors.New("row cells count does not match columns count in header")
+
+// row is returned row with its representation and values, if Rows implements [ValueRows].
+type row struct {
+	text   string
+	values []any
+}
+
+// queryRows returns all rows returned by query.
+// Rows implementing [ValueRows] are formatted by [FormatValue].
+// Columns are returned only if Rows implements [ColumnRows].
+func queryRows(ctx context.Context, tx Tx, sql string, args ...any) (columns []string, rs []row, err error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	if cr, ok := rows.(ColumnRows); ok {
+		columns, err = cr.Columns()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for rows.Next() {
+		r, err := readRow(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		rs = append(rs, r)
+	}
+	return columns, rs, rows.Err()
+}
+
+// queryTexts returns text of each row returned by query, like JSON document or line of plan.
+// Values of [ValueRows] are taken as is, other rows are stripped of brackets.
+func queryTexts(ctx context.Context, tx Tx, sql string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var texts []string
+	for rows.Next() {
+		v, err := rowText(rows)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, v)
+	}
+	return texts, rows.Err()
+}
+
+// cells returns cells of row for n columns, or nil if row can not be split into them.
+// Values are formatted by [FormatValue]. Representation of other rows is split by whitespaces,
+// which is reliable only if count of split cells matches n.
+func (r row) cells(n int) []string {
+	if r.values != nil {
+		cells := make([]string, len(r.values))
+		for i, v := range r.values {
+			cells[i] = FormatValue(v)
+		}
+		return cells
+	}
+	cells := strings.Fields(trimBrackets(r.text))
+	if len(cells) != n {
+		return nil
+	}
+	return cells
+}
+
+// matches reports whether row is equal to expected one.
+// Cells are compared one by one, if they are known.
+func (r row) matches(cells, columns []string) bool {
+	if columns == nil {
+		return r.text == cells[0]
+	}
+	if rc := r.cells(len(columns)); rc != nil {
+		return slices.Equal(rc, cells)
+	}
+	return r.text == formatCells(cells)
+}
+
+// rowTexts returns representations of rows.
+func rowTexts(rs []row) []string {
+	texts := make([]string, len(rs))
+	for i, r := range rs {
+		texts[i] = r.text
+	}
+	return texts
+}
+
+// compareColumns compares names of returned columns with expected ones.
+func compareColumns(got, want []string) error {
+	if slices.Equal(got, want) {
+		return nil
+	}
+	if len(got) == len(want) && !slices.ContainsFunc(want, func(c string) bool { return !slices.Contains(got, c) }) {
+		return fmt.Errorf("defined query returns columns in order %v, want %v", got, want)
+	}
+	var missing, unexpected []string
+	for _, c := range want {
+		if !slices.Contains(got, c) {
+			missing = append(missing, c)
+		}
+	}
+	for _, c := range got {
+		if !slices.Contains(want, c) {
+			unexpected = append(unexpected, c)
+		}
+	}
+	return fmt.Errorf("defined query returns columns %v, want %v (missing %v, unexpected %v)", got, want, missing, unexpected)
+}
+
+// parseTable parses block of expected rows, one row per line.
+//
+// If the first line is followed by psql-like separator (----+----),
+// it is a header with columns names and the rows are split into cells by |.
+// Otherwise each line is compared with row representation as is.
+func parseTable(src string) (columns []string, rows [][]string, err error) {
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Trim(line, " \t\r")
+	}
+	if len(lines) > 1 && isTableSeparator(lines[1]) {
+		columns = splitCells(lines[0])
+		lines = lines[2:]
+	}
+	rows = [][]string{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if columns == nil {
+			rows = append(rows, []string{line})
+			continue
+		}
+		cells := splitCells(line)
+		if len(cells) != len(columns) {
+			return nil, nil, fmt.Errorf("%w: %q", errTableCells, line)
+		}
+		rows = append(rows, cells)
+	}
+	return columns, rows, nil
+}
+
+func isTableSeparator(line string) bool {
+	return strings.Contains(line, "-") && strings.Trim(line, "-+| ") == ""
+}
+
+func splitCells(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.Trim(cell, " \t")
+	}
+	return cells
+}
+
+// formatCells returns row representation of expected cells,
+// the same as fmt prints slice of values.
+func formatCells(cells []string) string {
+	return "[" + strings.Join(cells, " ") + "]"
+}
+
+// trimBrackets returns row representation without brackets added by fmt or [formatCells].
+func trimBrackets(r string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(r, "["), "]")
+}
+
+// expectedRow returns representation of expected row to compare with Rows.String.
+func expectedRow(cells, columns []string) string {
+	if columns == nil {
+		return cells[0]
+	}
+	return formatCells(cells)
+}
+
+// compareRows compares returned rows with expected ones and reports the first difference with diff near it.
+func compareRows(got []row, want [][]string, columns []string) error {
+	for i := range max(len(got), len(want)) {
+		switch {
+		case i >= len(got):
+			return fmt.Errorf("defined query returns %d rows, want %d; missing row %d: %s\n%s",
+				len(got), len(want), i+1, expectedRow(want[i], columns), diffRows(got, want, columns, i))
+		case i >= len(want):
+			return fmt.Errorf("defined query returns %d rows, want %d; unexpected row %d: %s\n%s",
+				len(got), len(want), i+1, got[i].text, diffRows(got, want, columns, i))
+		}
+		if got[i].matches(want[i], columns) {
+			continue
+		}
+		w := expectedRow(want[i], columns)
+		var gc []string
+		if columns != nil {
+			gc = got[i].cells(len(columns))
+		}
+		if gc == nil {
+			return fmt.Errorf("row %d: defined query returns %q, want %q\n%s", i+1, got[i].text, w, diffRows(got, want, columns, i))
+		}
+		for j := range max(len(gc), len(want[i])) {
+			var g, w string
+			if j < len(gc) {
+				g = gc[j]
+			}
+			if j < len(want[i]) {
+				w = want[i][j]
+			}
+			if g == w {
+				continue
+			}
+			col := fmt.Sprintf("column %d", j+1)
+			if j < len(columns) {
+				col = fmt.Sprintf("column %d (%s)", j+1, columns[j])
+			}
+			return fmt.Errorf("row %d, %s: defined query returns %q, want %q\n%s", i+1, col, g, w, diffRows(got, want, columns, i))
+		}
+		return fmt.Errorf("row %d: defined query returns %q, want %q\n%s", i+1, got[i].text, w, diffRows(got, want, columns, i))
+	}
+	return nil
+}
+
+// compareRowsUnordered compares returned rows with expected ones as multisets.
+// If subset is true, returned rows may contain rows which are not expected.
+func compareRowsUnordered(got []row, want [][]string, columns []string, subset bool) error {
+	rest := slices.Clone(got)
+	var missing []string
+	for _, cells := range want {
+		if i := slices.IndexFunc(rest, func(r row) bool { return r.matches(cells, columns) }); i >= 0 {
+			rest = slices.Delete(rest, i, i+1)
+		} else {
+			missing = append(missing, expectedRow(cells, columns))
+		}
+	}
+	if subset {
+		rest = nil
+	}
+	if len(missing) == 0 && len(rest) == 0 {
+		return nil
+	}
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "defined query returns %d rows, want %d", len(got), len(want))
+	if subset {
+		s.WriteString(" or more")
+	}
+	for _, r := range missing[:min(len(missing), maxDiffRows)] {
+		fmt.Fprintf(s, "\n- %s", r)
+	}
+	for _, r := range rest[:min(len(rest), maxDiffRows)] {
+		fmt.Fprintf(s, "\n+ %s", r.text)
+	}
+	if len(missing) > maxDiffRows || len(rest) > maxDiffRows {
+		fmt.Fprintf(s, "\n... %d missing and %d unexpected rows are omitted",
+			max(0, len(missing)-maxDiffRows), max(0, len(rest)-maxDiffRows))
+	}
+	return errors.New(s.String())
+}
@@ -0,0 +1,263 @@
+package sqltest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_parseTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		wantColumns []string
+		wantRows    [][]string
+		wantErr     bool
+	}{
+		{
+			name:     "lines",
+			src:      "[1 a]\n\n [2 b] \n",
+			wantRows: [][]string{{"[1 a]"}, {"[2 b]"}},
+		},
+		{
+			name:        "header",
+			src:         " id | name\n----+------\n  1 | a\n  2 | b b",
+			wantColumns: []string{"id", "name"},
+			wantRows:    [][]string{{"1", "a"}, {"2", "b b"}},
+		},
+		{
+			name:        "header-borders",
+			src:         "| id |\n|----|\n| 1 |",
+			wantColumns: []string{"id"},
+			wantRows:    [][]string{{"1"}},
+		},
+		{
+			name:        "header-only",
+			src:         "id\n--",
+			wantColumns: []string{"id"},
+			wantRows:    [][]string{},
+		},
+		{
+			name:    "cells-count",
+			src:     "id | name\n---+-----\n1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotColumns, gotRows, gotErr := parseTable(tt.src)
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseTable() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseTable() succeeded unexpectedly")
+			}
+			if g, w := fmt.Sprintf("%q", gotColumns), fmt.Sprintf("%q", tt.wantColumns); g != w {
+				t.Errorf("parseTable() columns = %s, want %s", g, w)
+			}
+			if g, w := fmt.Sprintf("%q", gotRows), fmt.Sprintf("%q", tt.wantRows); g != w {
+				t.Errorf("parseTable() rows = %s, want %s", g, w)
+			}
+		})
+	}
+}
+
+func Test_compareRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     []row
+		want    [][]string
+		columns []string
+		wantErr string
+	}{
+		{
+			name: "equal-lines",
+			got:  textRows("[1 a]", "[2 b]"),
+			want: [][]string{{"[1 a]"}, {"[2 b]"}},
+		},
+		{
+			name:    "equal-cells",
+			got:     textRows("[1 a]", "[2 b]"),
+			want:    [][]string{{"1", "a"}, {"2", "b"}},
+			columns: []string{"id", "name"},
+		},
+		{
+			name:    "differ-line",
+			got:     textRows("[1 a]", "[2 b]"),
+			want:    [][]string{{"[1 a]"}, {"[2 c]"}},
+			wantErr: `row 2: defined query returns "[2 b]", want "[2 c]"`,
+		},
+		{
+			name:    "differ-cell",
+			got:     textRows("[1 a]", "[2 b]"),
+			want:    [][]string{{"1", "a"}, {"2", "c"}},
+			columns: []string{"id", "name"},
+			wantErr: `row 2, column 2 (name): defined query returns "b", want "c"`,
+		},
+		{
+			name:    "differ-spaces",
+			got:     textRows("[John Smith 1]"),
+			want:    [][]string{{"John Smith", "2"}},
+			columns: []string{"name", "id"},
+			wantErr: `row 1: defined query returns "[John Smith 1]", want "[John Smith 2]"`,
+		},
+		{
+			name:    "differ-value-cell",
+			got:     []row{{text: "[John Smith 1]", values: []any{"John Smith", 1}}},
+			want:    [][]string{{"John Smith", "2"}},
+			columns: []string{"name", "id"},
+			wantErr: `row 1, column 2 (id): defined query returns "1", want "2"`,
+		},
+		{
+			name:    "differ-value-split",
+			got:     []row{{text: "[a b c]", values: []any{"a b", "c"}}},
+			want:    [][]string{{"a", "b c"}},
+			columns: []string{"x", "y"},
+			wantErr: `row 1, column 1 (x): defined query returns "a b", want "a"`,
+		},
+		{
+			name:    "missing-row",
+			got:     textRows("[1 a]"),
+			want:    [][]string{{"[1 a]"}, {"[2 b]"}},
+			wantErr: `defined query returns 1 rows, want 2; missing row 2: [2 b]`,
+		},
+		{
+			name:    "unexpected-row",
+			got:     textRows("[1 a]", "[2 b]"),
+			want:    [][]string{{"1", "a"}},
+			columns: []string{"id", "name"},
+			wantErr: `defined query returns 2 rows, want 1; unexpected row 2: [2 b]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareRows(tt.got, tt.want, tt.columns)
+			var got, diff string
+			if err != nil {
+				got, diff, _ = strings.Cut(err.Error(), "\n")
+			}
+			if got != tt.wantErr {
+				t.Errorf("compareRows() = %q, want %q", got, tt.wantErr)
+			}
+			if err != nil && !strings.HasPrefix(diff, "diff (-want +got):") {
+				t.Errorf("compareRows() diff = %q, want diff (-want +got)", diff)
+			}
+		})
+	}
+}
+
+// textRows returns rows of adapter, which implements only Rows.String.
+func textRows(texts ...string) []row {
+	rs := make([]row, len(texts))
+	for i, t := range texts {
+		rs[i] = row{text: t}
+	}
+	return rs
+}
+
+func Test_compareColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     []string
+		want    []string
+		wantErr string
+	}{
+		{name: "equal", got: []string{"a", "b"}, want: []string{"a", "b"}},
+		{
+			name:    "reordered",
+			got:     []string{"b", "a"},
+			want:    []string{"a", "b"},
+			wantErr: "defined query returns columns in order [b a], want [a b]",
+		},
+		{
+			name:    "renamed",
+			got:     []string{"a", "c"},
+			want:    []string{"a", "b"},
+			wantErr: "defined query returns columns [a c], want [a b] (missing [b], unexpected [c])",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareColumns(tt.got, tt.want)
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("compareColumns() = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_compareRowsUnordered(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     []row
+		want    [][]string
+		subset  bool
+		wantErr string
+	}{
+		{
+			name: "equal-reordered",
+			got:  textRows("[2]", "[1]", "[1]"),
+			want: [][]string{{"[1]"}, {"[2]"}, {"[1]"}},
+		},
+		{
+			name:    "duplicates",
+			got:     textRows("[1]", "[2]"),
+			want:    [][]string{{"[1]"}, {"[1]"}},
+			wantErr: "defined query returns 2 rows, want 2\n- [1]\n+ [2]",
+		},
+		{
+			name:   "subset",
+			got:    textRows("[3]", "[1]", "[2]"),
+			want:   [][]string{{"[2]"}, {"[1]"}},
+			subset: true,
+		},
+		{
+			name:    "subset-missing",
+			got:     textRows("[3]", "[1]"),
+			want:    [][]string{{"[2]"}, {"[1]"}},
+			subset:  true,
+			wantErr: "defined query returns 2 rows, want 2 or more\n- [2]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareRowsUnordered(tt.got, tt.want, nil, tt.subset)
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("compareRowsUnordered() = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_compareRowsUnordered_omitted(t *testing.T) {
+	var got []row
+	var want [][]string
+	for i := range maxDiffRows + 5 {
+		got = append(got, row{text: fmt.Sprintf("[%d]", i)})
+		if i < maxDiffRows+2 {
+			want = append(want, []string{fmt.Sprintf("[%d]", -i)})
+		}
+	}
+	err := compareRowsUnordered(got, want, nil, false)
+	if err == nil {
+		t.Fatal("compareRowsUnordered() succeeded unexpectedly")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if g, w := len(lines), 2*maxDiffRows+2; g != w {
+		t.Errorf("compareRowsUnordered() returns %d lines, want %d", g, w)
+	}
+	if g, w := lines[len(lines)-1], "... 1 missing and 4 unexpected rows are omitted"; g != w {
+		t.Errorf("compareRowsUnordered() ends with %q, want %q", g, w)
+	}
+}
@@ -0,0 +1,60 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+)
+
+// SavepointDialect builds savepoint statements for database.
+type SavepointDialect interface {
+	Savepoint(name string) string
+	RollbackTo(name string) string
+	Release(name string) string
+}
+
+// StandardSavepoints builds savepoint statements with SQL standard syntax,
+// which is supported by PostgreSQL, MySQL and SQLite.
+var StandardSavepoints SavepointDialect = standardSavepoints{}
+
+type standardSavepoints struct{}
+
+func (standardSavepoints) Savepoint(name string) string  { return "SAVEPOINT " + name }
+func (standardSavepoints) RollbackTo(name string) string { return "ROLLBACK TO SAVEPOINT " + name }
+func (standardSavepoints) Release(name string) string    { return "RELEASE SAVEPOINT " + name }
+
+// FailingQuerier is optionally implemented by [Querier] which statement is expected to fail.
+//
+// If savepoints are enabled by [WithSavepoints], Test.Run wraps such queriers into savepoint,
+// so an expected error does not abort the whole transaction.
+type FailingQuerier interface {
+	Querier
+	ExpectsError() bool
+}
+
+// Isolate queriers expecting errors in savepoints built by dialect.
+// If dialect is nil, [StandardSavepoints] are used.
+func WithSavepoints(dialect SavepointDialect) Option {
+	if dialect == nil {
+		dialect = StandardSavepoints
+	}
+	return func(pc *parseConfig) {
+		pc.savepoints = dialect
+	}
+}
+
+const savepointName = "sqltest_savepoint"
+
+// withSavepoint calls querier inside savepoint and rolls back to it afterwards.
+func withSavepoint(ctx context.Context, tx Tx, dialect SavepointDialect, querier Querier) error {
+	if err := tx.Exec(ctx, dialect.Savepoint(savepointName)); err != nil {
+		return fmt.Errorf("savepoint: %v", err)
+	}
+	qerr := querier.Query(ctx, tx)
+	if err := tx.Exec(ctx, dialect.RollbackTo(savepointName)); err != nil {
+		return fmt.Errorf("rollback to savepoint: %v", err)
+	}
+	if err := tx.Exec(ctx, dialect.Release(savepointName)); err != nil {
+		return fmt.Errorf("release savepoint: %v", err)
+	}
+	return qerr
+}
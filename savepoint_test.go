@@ -0,0 +1,67 @@
+package sqltest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithSavepoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		src       string
+		wantExecs []string
+		wantErr   bool
+	}{
+		{
+			name:      "disabled",
+			src:       "except duplicate\nINSERT 1;\nSELECT 1",
+			wantExecs: []string{"INSERT 1", "SELECT 1"},
+		},
+		{
+			name: "enabled",
+			opts: []Option{WithSavepoints(nil)},
+			src:  "SELECT 1;\nexcept duplicate\nINSERT 1;\nSELECT 2",
+			wantExecs: []string{
+				"SELECT 1",
+				"SAVEPOINT sqltest_savepoint",
+				"INSERT 1",
+				"ROLLBACK TO SAVEPOINT sqltest_savepoint",
+				"RELEASE SAVEPOINT sqltest_savepoint",
+				"SELECT 2",
+			},
+		},
+		{
+			name: "unexpected-success",
+			opts: []Option{WithSavepoints(nil)},
+			src:  "except duplicate\nSELECT 1;\nSELECT 2",
+			wantExecs: []string{
+				"SAVEPOINT sqltest_savepoint",
+				"SELECT 1",
+				"ROLLBACK TO SAVEPOINT sqltest_savepoint",
+				"RELEASE SAVEPOINT sqltest_savepoint",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src), tt.opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			tx := &fakeTx{errs: map[string]error{"INSERT 1": errors.New("duplicate key")}}
+			gotErr := test.Run(tx)
+			if gotErr != nil && !tt.wantErr {
+				t.Errorf("Run() failed: %v", gotErr)
+			}
+			if gotErr == nil && tt.wantErr {
+				t.Error("Run() succeeded unexpectedly")
+			}
+			if g, w := strings.Join(tx.execs, "; "), strings.Join(tt.wantExecs, "; "); g != w {
+				t.Errorf("Run() executes %q, want %q", g, w)
+			}
+		})
+	}
+}
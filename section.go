@@ -0,0 +1,118 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	sectionKey = "test "
+	sectionLen = len(sectionKey)
+)
+
+var (
+	errSectionName   = errors.New("malformed name in test statement, it should be double quoted")
+	errSectionDouble = errors.New("duplicate name in test statement")
+)
+
+// section is a named part of test, started by test "name" statement.
+// Queries before the first section are preamble, which runs before each section.
+type section struct {
+	name    string
+	markers markers
+}
+
+// parseSection returns name of section from test "name" statement.
+func parseSection(src []byte) (string, bool, error) {
+	if !bytes.HasPrefix(src, []byte(sectionKey)) {
+		return "", false, nil
+	}
+	name, err := strconv.Unquote(strings.Trim(string(src[sectionLen:]), " \t\r\n"))
+	if err != nil || name == "" || strings.ContainsRune(name, '/') {
+		return "", true, errSectionName
+	}
+	return name, true, nil
+}
+
+// addSection starts new section of test.
+func (test *Test) addSection(name string, m markers) error {
+	if slices.ContainsFunc(test.sections, func(s section) bool { return s.name == name }) {
+		return fmt.Errorf("%w: %q", errSectionDouble, name)
+	}
+	test.sections = append(test.sections, section{name: name, markers: m})
+	return nil
+}
+
+// selectSections skips sections, which are not marked as only, if any section of test is marked so.
+func (test *Test) selectSections() {
+	if !test.onlySections() {
+		return
+	}
+	for i, s := range test.sections {
+		if !s.markers.only && !s.markers.skip {
+			test.sections[i].markers.skip = true
+			test.sections[i].markers.reason = "other sections of test are marked as only"
+		}
+	}
+}
+
+// onlySections reports whether any section of test is marked as only.
+func (test *Test) onlySections() bool {
+	return slices.ContainsFunc(test.sections, func(s section) bool { return s.markers.only })
+}
+
+// Sections returns runners of named sections of test.
+// Each of them runs preamble queries and queries of the section.
+func (test *Test) Sections() iter.Seq2[string, TestRunner] {
+	return func(yield func(string, TestRunner) bool) {
+		for i, s := range test.sections {
+			if !yield(s.name, &sectionRunner{test: test, section: i + 1}) {
+				return
+			}
+		}
+	}
+}
+
+type sectionRunner struct {
+	test    *Test
+	section int
+}
+
+// Run implements TestRunner.
+func (r *sectionRunner) Run(tx Tx) error {
+	if r.test.concurrent {
+		return errSessionsNeeded
+	}
+	return r.run(single(tx))
+}
+
+// RunSessions runs queries of section like [Test.RunSessions].
+func (r *sectionRunner) RunSessions(factory TxFactory) error {
+	return r.run(newSessions(factory))
+}
+
+func (r *sectionRunner) run(ss *sessions) error {
+	if r.test.markers.skip {
+		return &SkipError{Reason: r.test.markers.reason}
+	}
+	m := r.test.sections[r.section-1].markers
+	m.todo = m.todo || r.test.markers.todo
+	return runMarked(m, func() error {
+		return r.test.run(ss, r.filter)
+	})
+}
+
+// filter selects queries of preamble and section.
+func (r *sectionRunner) filter(q query) bool {
+	return q.section == 0 || q.section == r.section
+}
+
+var (
+	_ TestRunner    = (*sectionRunner)(nil)
+	_ SessionRunner = (*sectionRunner)(nil)
+)
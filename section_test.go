@@ -0,0 +1,151 @@
+package sqltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_parseSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantOk  bool
+		wantErr bool
+	}{
+		{name: "other", src: "SELECT 1"},
+		{name: "ok", src: `test "insert logs salary"`, want: "insert logs salary", wantOk: true},
+		{name: "escaped", src: `test "say \"hi\""` + "\n", want: `say "hi"`, wantOk: true},
+		{name: "unquoted", src: "test insert", wantOk: true, wantErr: true},
+		{name: "empty", src: `test ""`, wantOk: true, wantErr: true},
+		{name: "slash", src: `test "a/b"`, wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk, gotErr := parseSection([]byte(tt.src))
+			if gotOk != tt.wantOk {
+				t.Errorf("parseSection() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseSection() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseSection() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("parseSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTest_Sections(t *testing.T) {
+	src := "CREATE TABLE t (a int);\n" +
+		"test \"first\";\nINSERT INTO t VALUES (1);\n" +
+		"-- sqltest:skip\ntest \"second\";\nINSERT INTO t VALUES (2);\n" +
+		"test \"third\";\nINSERT INTO t VALUES (3)"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tests := []struct {
+		name      string
+		wantExecs []string
+		wantSkip  bool
+	}{
+		{name: "first", wantExecs: []string{"CREATE TABLE t (a int)", "INSERT INTO t VALUES (1)"}},
+		{name: "second", wantSkip: true},
+		{name: "third", wantExecs: []string{"CREATE TABLE t (a int)", "INSERT INTO t VALUES (3)"}},
+	}
+	var i int
+	for name, runner := range test.Sections() {
+		tt := tests[i]
+		i++
+		if name != tt.name {
+			t.Fatalf("Sections() yields %q, want %q", name, tt.name)
+		}
+		tx := &fakeTx{}
+		gotErr := runner.Run(tx)
+		if g := errors.Is(gotErr, ErrSkip); g != tt.wantSkip || !g && gotErr != nil {
+			t.Errorf("Run() of section %q = %v, want skipped %v", name, gotErr, tt.wantSkip)
+		}
+		if g, w := strings.Join(tx.execs, "; "), strings.Join(tt.wantExecs, "; "); g != w {
+			t.Errorf("Run() of section %q executes %q, want %q", name, g, w)
+		}
+	}
+	if i != len(tests) {
+		t.Errorf("Sections() yields %d sections, want %d", i, len(tests))
+	}
+
+	tx := &fakeTx{}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	want := "CREATE TABLE t (a int); INSERT INTO t VALUES (1); INSERT INTO t VALUES (3)"
+	if g := strings.Join(tx.execs, "; "); g != want {
+		t.Errorf("Run() executes %q, want %q", g, want)
+	}
+}
+
+func TestNew_sectionDuplicate(t *testing.T) {
+	_, err := New(strings.NewReader("test \"a\";\nSELECT 1;\ntest \"a\";\nSELECT 2"))
+	if !errors.Is(err, errSectionDouble) {
+		t.Errorf("New() error = %v, want %v", err, errSectionDouble)
+	}
+}
+
+func TestSet_All_sections(t *testing.T) {
+	set, err := NewSet(iter.Seq2[string, io.Reader](func(yield func(string, io.Reader) bool) {
+		_ = yield("a.sql", strings.NewReader("SELECT 1")) &&
+			yield("b.sql", strings.NewReader("SELECT 0;\ntest \"x\";\nSELECT 1;\ntest \"y\";\nSELECT 2"))
+	}))
+	if err != nil {
+		t.Fatalf("NewSet() failed: %v", err)
+	}
+	var got []string
+	for name := range set.All() {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	if g, w := strings.Join(got, ", "), "a.sql, b.sql/x, b.sql/y"; g != w {
+		t.Errorf("All() = %q, want %q", g, w)
+	}
+}
+
+func TestSet_All_onlySection(t *testing.T) {
+	set, err := NewSet(iter.Seq2[string, io.Reader](func(yield func(string, io.Reader) bool) {
+		_ = yield("a.sql", strings.NewReader("SELECT 1")) &&
+			yield("b.sql", strings.NewReader("SELECT 0;\ntest \"x\";\nSELECT 1;\n-- sqltest:only\ntest \"y\";\nSELECT 2"))
+	}))
+	if err != nil {
+		t.Fatalf("NewSet() failed: %v", err)
+	}
+	var got []string
+	for name, runner := range set.All() {
+		tx := &fakeTx{}
+		err := runner.Run(tx)
+		got = append(got, fmt.Sprintf("%s: %v %q", name, errors.Is(err, ErrSkip), tx.execs))
+	}
+	slices.Sort(got)
+	want := `a.sql: true [], b.sql/x: true [], b.sql/y: false ["SELECT 0" "SELECT 2"]`
+	if g := strings.Join(got, ", "); g != want {
+		t.Errorf("All() runs %s, want %s", g, want)
+	}
+
+	test := set.tests["b.sql"]
+	tx := &fakeTx{}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if g, w := strings.Join(tx.execs, "; "), "SELECT 0; SELECT 2"; g != w {
+		t.Errorf("Run() executes %q, want %q", g, w)
+	}
+}
@@ -0,0 +1,157 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	sessionKey = "session "
+	asyncKey   = "async"
+	awaitKey   = "await"
+)
+
+var (
+	errSessionName    = errors.New("malformed session name")
+	errSessionsNeeded = errors.New("test uses sessions, it should be run with RunSessions")
+	errSessionBusy    = errors.New("session has pending statement, it should be awaited first")
+	errAwaitNothing   = errors.New("session has no pending statement to await")
+	errNotAwaited     = errors.New("pending statement is not awaited")
+)
+
+// TxFactory returns transaction of session by its name.
+// Statements before the first session statement run in session with empty name.
+type TxFactory func(session string) (Tx, error)
+
+// parseSession returns session name from session statement.
+func parseSession(src []byte) (string, bool, error) {
+	if !bytes.HasPrefix(src, []byte(sessionKey)) {
+		return "", false, nil
+	}
+	name := strings.Trim(string(src[len(sessionKey):]), " \t\r\n")
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return "", true, errSessionName
+	}
+	return name, true, nil
+}
+
+// parseAwait returns session name from await statement.
+// If name is omitted, current session is awaited.
+func parseAwait(src []byte) (string, bool, error) {
+	if !bytes.HasPrefix(src, []byte(awaitKey)) {
+		return "", false, nil
+	}
+	rest := string(src[len(awaitKey):])
+	if rest != "" && !strings.ContainsRune(" \t\r\n", rune(rest[0])) {
+		return "", false, nil
+	}
+	name := strings.Trim(rest, " \t\r\n")
+	if strings.ContainsAny(name, " \t\r\n") {
+		return "", true, errSessionName
+	}
+	return name, true, nil
+}
+
+// parseAsync cuts async line preceding statement, which runs in background until await.
+// It returns length of the cut line.
+func parseAsync(src []byte) (int, bool) {
+	rest, ok := bytes.CutPrefix(src, []byte(asyncKey))
+	if !ok {
+		return 0, false
+	}
+	rest = bytes.TrimLeft(rest, " \t\r")
+	if len(rest) == 0 || rest[0] != '\n' {
+		return 0, false
+	}
+	return len(src) - len(rest) + 1, true
+}
+
+// RunSessions runs queries of test, executing each of them in transaction of its session.
+// Transactions are requested from factory once per session on first use.
+func (test *Test) RunSessions(factory TxFactory) error {
+	return runMarked(test.markers, func() error {
+		return test.run(newSessions(factory), test.filter)
+	})
+}
+
+// sessions holds transactions and pending statements of sessions.
+type sessions struct {
+	factory TxFactory
+	txs     map[string]Tx
+	pending map[string]*pending
+}
+
+// pending is statement running in background.
+type pending struct {
+	q      query
+	done   chan error
+	cancel context.CancelFunc
+}
+
+func newSessions(factory TxFactory) *sessions {
+	return &sessions{factory: factory, txs: make(map[string]Tx), pending: make(map[string]*pending)}
+}
+
+// single returns sessions of test without session statements.
+func single(tx Tx) *sessions {
+	return newSessions(func(string) (Tx, error) { return tx, nil })
+}
+
+// tx returns transaction of session name.
+func (s *sessions) tx(name string) (Tx, error) {
+	if tx, ok := s.txs[name]; ok {
+		return tx, nil
+	}
+	tx, err := s.factory(name)
+	if err != nil {
+		return nil, fmt.Errorf("session %q: %v", name, err)
+	}
+	s.txs[name] = tx
+	return tx, nil
+}
+
+// await returns pending statement of session.
+func (s *sessions) await(name string) *pending {
+	p := s.pending[name]
+	delete(s.pending, name)
+	return p
+}
+
+// start calls query in background with deadline of timeout.
+// If statement should be blocked, start waits for it and returns error if it completes.
+func (s *sessions) start(ctx context.Context, q query, timeout time.Duration, call func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	p := &pending{q: q, done: make(chan error, 1), cancel: cancel}
+	go func() {
+		defer cancel()
+		p.done <- call(ctx)
+	}()
+	if q.blocked > 0 {
+		select {
+		case err := <-p.done:
+			if err != nil {
+				return fmt.Errorf("%w during %v, it fails: %v", errNotBlocked, q.blocked, err)
+			}
+			return fmt.Errorf("%w during %v", errNotBlocked, q.blocked)
+		case <-time.After(q.blocked):
+		}
+	}
+	s.pending[q.session] = p
+	return nil
+}
+
+// stop cancels pending statements and waits for them to return,
+// so transactions are not used after test run.
+func (s *sessions) stop() {
+	for name, p := range s.pending {
+		p.cancel()
+		<-p.done
+		delete(s.pending, name)
+	}
+}
+
+var _ SessionRunner = (*Test)(nil)
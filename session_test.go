@@ -0,0 +1,181 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_parseAwait(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantOk  bool
+		wantErr bool
+	}{
+		{name: "other", src: "awaiting()"},
+		{name: "current", src: "await", wantOk: true},
+		{name: "named", src: "await B\n", want: "B", wantOk: true},
+		{name: "malformed", src: "await B C", wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk, gotErr := parseAwait([]byte(tt.src))
+			if gotOk != tt.wantOk {
+				t.Errorf("parseAwait() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("parseAwait() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("parseAwait() succeeded unexpectedly")
+			}
+			if got != tt.want {
+				t.Errorf("parseAwait() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseAsync(t *testing.T) {
+	tests := []struct {
+		src    string
+		want   int
+		wantOk bool
+	}{
+		{src: "SELECT 1"},
+		{src: "async"},
+		{src: "asynchronous\nSELECT 1"},
+		{src: "async\nSELECT 1", want: 6, wantOk: true},
+		{src: "async \r\nSELECT 1", want: 8, wantOk: true},
+	}
+	for _, tt := range tests {
+		got, gotOk := parseAsync([]byte(tt.src))
+		if got != tt.want || gotOk != tt.wantOk {
+			t.Errorf("parseAsync(%q) = %d, %v, want %d, %v", tt.src, got, gotOk, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestTest_RunSessions(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantExecs map[string]string
+		wantErr   error
+	}{
+		{
+			name: "sessions",
+			src: "CREATE 1;\nsession A;\nUPDATE 1;\nsession B;\nasync\nUPDATE 2;\n" +
+				"session A;\nCOMMIT;\nawait B;\nSELECT 2",
+			wantExecs: map[string]string{"": "CREATE 1", "A": "UPDATE 1; COMMIT; SELECT 2", "B": "UPDATE 2"},
+		},
+		{
+			name:    "busy",
+			src:     "session B;\nasync\nUPDATE 2;\nSELECT 2;\nawait",
+			wantErr: errSessionBusy,
+		},
+		{
+			name:      "await",
+			src:       "session A;\nasync\nUPDATE 1;\nawait;\nSELECT 1",
+			wantExecs: map[string]string{"A": "UPDATE 1; SELECT 1"},
+		},
+		{
+			name:      "await-failed",
+			src:       "session A;\nasync\nFAIL;\nawait;\nSELECT 1",
+			wantExecs: map[string]string{"A": "FAIL"},
+			wantErr:   errFakeFail,
+		},
+		{
+			name:    "await-nothing",
+			src:     "session A;\nawait B",
+			wantErr: errAwaitNothing,
+		},
+		{
+			name:    "not-awaited",
+			src:     "session A;\nasync\nUPDATE 1",
+			wantErr: errNotAwaited,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := New(strings.NewReader(tt.src))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			txs := make(map[string]*fakeTx)
+			gotErr := test.RunSessions(func(session string) (Tx, error) {
+				txs[session] = &fakeTx{errs: map[string]error{"FAIL": errFakeFail}}
+				return txs[session], nil
+			})
+			if tt.wantErr == nil && gotErr != nil || tt.wantErr != nil && (gotErr == nil || !strings.Contains(gotErr.Error(), tt.wantErr.Error())) {
+				t.Errorf("RunSessions() error = %v, want %v", gotErr, tt.wantErr)
+			}
+			if tt.wantExecs == nil {
+				return
+			}
+			got := make(map[string]string)
+			for name, tx := range txs {
+				got[name] = strings.Join(tx.execs, "; ")
+			}
+			for name, w := range tt.wantExecs {
+				if g := got[name]; g != w {
+					t.Errorf("RunSessions() executes %q in session %q, want %q", g, name, w)
+				}
+			}
+			if len(got) != len(tt.wantExecs) {
+				t.Errorf("RunSessions() opens sessions %v, want %v", got, tt.wantExecs)
+			}
+		})
+	}
+}
+
+func TestTest_Run_sessions(t *testing.T) {
+	test, err := New(strings.NewReader("session A;\nSELECT 1"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if err := test.Run(&fakeTx{}); !errors.Is(err, errSessionsNeeded) {
+		t.Errorf("Run() error = %v, want %v", err, errSessionsNeeded)
+	}
+}
+
+func TestTest_RunSessions_stopPending(t *testing.T) {
+	test, err := New(strings.NewReader("session B;\nexpect_blocked 10ms\nLOCK;\nsession A;\nFAIL;\nawait B"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	var locks atomic.Int32
+	released := make(chan struct{})
+	err = test.RunSessions(func(string) (Tx, error) {
+		return &stopTx{lockTx: lockTx{fakeTx: fakeTx{errs: map[string]error{"FAIL": errFakeFail}}, released: released}, locks: &locks}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), errFakeFail.Error()) {
+		t.Errorf("RunSessions() error = %v, want %v", err, errFakeFail)
+	}
+	if n := locks.Load(); n != 0 {
+		t.Errorf("RunSessions() returns with %d pending statements", n)
+	}
+}
+
+// stopTx counts LOCK statements which are still running.
+type stopTx struct {
+	lockTx
+	locks *atomic.Int32
+}
+
+func (tx *stopTx) Exec(ctx context.Context, sql string, args ...any) error {
+	if sql == "LOCK" {
+		tx.locks.Add(1)
+		defer tx.locks.Add(-1)
+	}
+	return tx.lockTx.Exec(ctx, sql, args...)
+}
+
+var errFakeFail = errors.New("fail")
@@ -18,18 +18,33 @@ type TestRunner interface {
 	Run(tx Tx) error
 }
 
+// SessionRunner is implemented by runners of [Set], which may run tests with several sessions.
+type SessionRunner interface {
+	RunSessions(factory TxFactory) error
+}
+
+// All returns runners of tests in set.
+// Tests with sections are represented by their sections, named as test/section.
 func (set *Set) All() iter.Seq2[string, TestRunner] {
 	return func(yield func(string, TestRunner) bool) {
 		for name, test := range set.tests {
-			if !yield(name, test) {
-				return
+			if len(test.sections) == 0 {
+				if !yield(name, test) {
+					return
+				}
+				continue
+			}
+			for section, runner := range test.Sections() {
+				if !yield(name+"/"+section, runner) {
+					return
+				}
 			}
 		}
 	}
 }
 
 // NewSet creates set for tests provided by tp.
-func NewSet(tp iter.Seq2[string, io.Reader], opts ...option) (*Set, error) {
+func NewSet(tp iter.Seq2[string, io.Reader], opts ...Option) (*Set, error) {
 	set := &Set{tests: make(map[string]*Test)}
 	var err error
 	for name, reader := range tp {
@@ -41,10 +56,20 @@ func NewSet(tp iter.Seq2[string, io.Reader], opts ...option) (*Set, error) {
 	if len(set.tests) == 0 {
 		return nil, errors.New("test set is empty")
 	}
+	only := false
+	for _, test := range set.tests {
+		only = only || test.markers.only || test.onlySections()
+	}
+	for _, test := range set.tests {
+		if only && !test.markers.only && !test.onlySections() && !test.markers.skip {
+			test.markers.skip = true
+			test.markers.reason = "other tests of set are marked as only"
+		}
+	}
 	return set, nil
 }
 
-func NewFileSet(pattern string, opts ...option) (*Set, error) {
+func NewFileSet(pattern string, opts ...Option) (*Set, error) {
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, err
@@ -63,6 +88,6 @@ func NewFileSet(pattern string, opts ...option) (*Set, error) {
 	}, opts...)
 }
 
-func DefaultFileSet(opts ...option) (*Set, error) {
+func DefaultFileSet(opts ...Option) (*Set, error) {
 	return NewFileSet(path.Join("testdata", "*.sql"), opts...)
 }
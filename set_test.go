@@ -22,7 +22,7 @@ func TestNewSet(t *testing.T) {
 	tests := []struct {
 		name    string
 		tp      iter.Seq2[string, io.Reader]
-		opts    []option
+		opts    []Option
 		want    map[string]*Test
 		wantErr bool
 	}{
@@ -84,7 +84,7 @@ func TestNewFileSet(t *testing.T) {
 	tests := []struct {
 		name    string
 		pattern string
-		opts    []option
+		opts    []Option
 		want    map[string]*Test
 		wantErr bool
 	}{
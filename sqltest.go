@@ -1,16 +1,21 @@
 package sqltest
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 var errTestEmpty = errors.New("not found queries for test")
 
 // Create new [Test] with queries from reader delimited by ;\n
-func New(reader io.Reader, opts ...option) (*Test, error) {
+func New(reader io.Reader, opts ...Option) (*Test, error) {
 	test := new(Test)
 	config := newParserConfig(opts...)
 	source, err := io.ReadAll(reader)
@@ -18,13 +23,51 @@ func New(reader io.Reader, opts ...option) (*Test, error) {
 		return nil, err
 	}
 	test.context = context.Background()
+	test.savepoints = config.savepoints
+	if config.update || *updateFlag {
+		test.update = newUpdater()
+	}
+	test.sectionReset = config.sectionReset
+	test.blockTimeout = config.blockTimeout
+	test.budget = config.maxDuration
+	test.durations = config.durations
+	test.strictNotices = config.strictNotices
+	test.file = config.file
+	if f, ok := reader.(interface{ Name() string }); ok && test.file == "" {
+		test.file = f.Name()
+	}
+	var includes []string
+	if test.file != "" {
+		includes = append(includes, filepath.Clean(test.file))
+	}
+	if err := test.parse(config, test.file, source, includes, true); err != nil {
+		return nil, err
+	}
+	if len(test.queries) == 0 {
+		return nil, errTestEmpty
+	}
+	test.selectSections()
+	return test, nil
+}
+
+// parse appends queries from source of file to the test.
+// Includes is the stack of files being parsed, used for cycles detection.
+// Root is set for the file the test is created from, as opposed to included files.
+func (test *Test) parse(config *parseConfig, file string, source []byte, includes []string, root bool) error {
 	end := len(source)
 	var off position
+	if test.update != nil {
+		test.update.sources[file] = source
+	}
+	// Markers of the first statement in root file apply to the whole test.
+	first := root
+	var pending markers
+	limit := config.limit
 	for {
-		if config.limit == 0 {
+		if limit == 0 {
 			panic("cycle limit reached")
 		}
-		config.limit--
+		limit--
 		if off.index >= end {
 			break
 		}
@@ -40,21 +83,118 @@ func New(reader io.Reader, opts ...option) (*Test, error) {
 			left:   off,
 			right:  off.add(right),
 			source: source[:right.index],
+			file:   file,
 		}
 		source = source[right.index:]
 		off = q.right
+		if config.templateData != nil {
+			expanded, err := expandTemplate(q.source, config.templateData)
+			if err != nil {
+				return fmt.Errorf("%s: %w", location(file, q.left.line), err)
+			}
+			q.expanded = !bytes.Equal(expanded, q.source)
+			q.source = expanded
+		}
 		psrc := q.source
+		var line int
 		if l := skipCommentaries(q.source); l.index > 0 {
+			if err := parseMarkers(q.source[:l.index], &pending); err != nil {
+				return fmt.Errorf("%s: %w", location(file, q.left.line), err)
+			}
 			if len(psrc) == l.index {
 				continue
 			}
 			psrc = psrc[l.index:]
+			q.offset = l.index
+			line = l.line
+		}
+		if first {
+			test.markers, pending = pending, markers{}
+			first = false
+		}
+		q.markers, pending = pending, markers{}
+		name, ok, err := parseSection(psrc)
+		if err == nil && ok {
+			err = test.addSection(name, q.markers)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+		}
+		if ok {
+			continue
+		}
+		q.section = len(test.sections)
+		if isReset(psrc) {
+			q.reset = true
+			test.queries = append(test.queries, q)
+			continue
+		}
+		name, ok, err = parseSession(psrc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+		}
+		if ok {
+			config.session = name
+			test.concurrent = true
+			continue
+		}
+		q.session = config.session
+		name, ok, err = parseAwait(psrc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+		}
+		if ok {
+			if name != "" {
+				q.session = name
+			}
+			q.await = true
+			test.concurrent = true
+			test.queries = append(test.queries, q)
+			continue
+		}
+		// Source of statement is cut after directive lines.
+		cut := false
+		for {
+			n, text, ok, err := parseNotice(psrc)
+			if err != nil {
+				return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+			}
+			if !ok {
+				break
+			}
+			psrc = psrc[n:]
+			q.offset += n
+			q.notices = append(q.notices, text)
+			line++
+			cut = true
+		}
+		n, blocked, ok, err := parseBlocked(psrc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+		}
+		if !ok {
+			n, ok = parseAsync(psrc)
+		}
+		if ok {
+			psrc = psrc[n:]
+			q.offset += n
+			q.async = true
+			q.blocked = blocked
+			line++
+			test.concurrent = true
+			cut = true
+		}
+		if name, ok := parseInclude(psrc); ok {
+			if err := test.include(config, file, name, includes); err != nil {
+				return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+			}
+			continue
 		}
 		parsed := false
 		for _, parser := range config.parsers {
 			nctx, que, err := parser.Parse(test.context, psrc)
 			if err != nil {
-				return nil, err
+				return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
 			}
 			if nctx != nil {
 				test.context = nctx
@@ -70,44 +210,129 @@ func New(reader io.Reader, opts ...option) (*Test, error) {
 		}
 		if !parsed {
 			q.querier = &execQuerier{q.source}
+			if cut {
+				q.querier = &execQuerier{psrc}
+			}
 			test.queries = append(test.queries, q)
 		}
+		if err := checkVars(test.context, string(psrc)); err != nil {
+			return fmt.Errorf("%s: %w", location(file, q.left.line+line), err)
+		}
 	}
-	if len(test.queries) == 0 {
-		return nil, errTestEmpty
+	return nil
+}
+
+// location returns human readable position of zero based line in file.
+func location(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line+1)
 	}
-	return test, nil
+	return fmt.Sprintf("%s:%d", file, line+1)
 }
 
 // Overwrite parsing cycles limit.
-func WithLimit(limit int) option {
+func WithLimit(limit int) Option {
 	return func(pc *parseConfig) {
 		pc.limit = limit
 	}
 }
 
+// Overwrite delimiter which splits source into queries.
+func WithDelimiter(delimiter QueryDelimiter) Option {
+	return func(pc *parseConfig) {
+		pc.delimiter = delimiter
+	}
+}
+
+// Overwrite parsers chain. Parsers are tried in the given order.
+func WithParsers(parsers ...QueryParser) Option {
+	return func(pc *parseConfig) {
+		pc.parsers = parsers
+	}
+}
+
+// Append parsers to the end of parsers chain.
+func WithExtraParsers(parsers ...QueryParser) Option {
+	return func(pc *parseConfig) {
+		pc.extra = append(pc.extra, parsers...)
+	}
+}
+
 type Test struct {
 	// Test context accumulate test data.
 	// This context passed into Query calls.
 	context context.Context
 	queries []query
+	// Dialect of savepoints around queriers expecting errors, if enabled.
+	savepoints SavepointDialect
+	// Name of file from which test was read.
+	file string
+	// Rewriter of expected values in files instead of comparing them, if update mode is enabled.
+	update *updater
+	// Markers of the whole test.
+	markers markers
+	// Named sections of test.
+	sections []section
+	// Roll back changes of each section.
+	sectionReset bool
+	// Test uses session, async or await statements.
+	concurrent bool
+	// Timeout of statements in background.
+	blockTimeout time.Duration
+	// Default budget of statements duration and reporter of measured durations.
+	budget    time.Duration
+	durations func(QueryDuration)
+	// Fail queries raising unexpected warnings.
+	strictNotices bool
 }
 
 type query struct {
 	left, right position
 	source      []byte
 	querier     Querier
+	// Offset of parsed source after leading commentaries.
+	offset int
+	// Name of file from which query was read.
+	file string
+	// Source differs from file contents after template expansion.
+	expanded bool
+	markers  markers
+	// Index of section starting from 1, or 0 for preamble.
+	section int
+	// Query is reset statement without querier.
+	reset bool
+	// Name of session in which query runs.
+	session string
+	// Query runs in background until await statement.
+	async bool
+	// Duration during which async query should be blocked.
+	blocked time.Duration
+	// Texts of notices expected from query.
+	notices []string
+	// Query is await statement of pending query in session.
+	await bool
 }
 
 type QueryParser interface {
 	// Parse is trying to parse query source to Querier object or updated context.
 	//
 	// If query source is not related to QueryParser implementation, it should return nils.
-	// That is, Parse returns either the context, or the Querier, or nothing.
+	// That is, Parse returns the context, the Querier, both of them, or nothing.
 	// If it returns not nil, then the processing of the current query will be stopped at this QueryParser.
 	Parse(context.Context, []byte) (context.Context, Querier, error)
 }
 
+// DirectiveParser is optionally implemented by [QueryParser] to declare keys of its directives,
+// which lines precede statements and are not SQL.
+// Default delimiter does not track SQL quotes in such lines, so they may contain apostrophes.
+type DirectiveParser interface {
+	QueryParser
+
+	// Directives returns keys of header directives, which lines are followed by SQL,
+	// and keys of data directives, which lines are followed by expected values up to the delimiter.
+	Directives() (header, data []string)
+}
+
 // Querier is query'like object. Which whould be called in Test.Run.
 type Querier interface {
 	// Query called in Test.Run. Querier implementation do some query and check it results.
@@ -133,27 +358,203 @@ type Rows interface {
 	String() (string, error)
 }
 
+// ColumnRows is optionally implemented by [Rows] to expose names of returned columns.
+//
+// If Rows implements it, asserts with header check names and order of columns.
+type ColumnRows interface {
+	Rows
+
+	// Columns returns names of columns.
+	Columns() ([]string, error)
+}
+
+// ValueRows is optionally implemented by [Rows] to expose typed values of row.
+//
+// If Rows implements it, values are formatted by [FormatValue] instead of String,
+// so expected values do not depend on driver.
+type ValueRows interface {
+	Rows
+
+	// Values returns values of current row.
+	Values() ([]any, error)
+}
+
+// Run queries of test. Sections of test run one by one after preamble.
 func (test *Test) Run(tx Tx) error {
-	var err error
+	if test.concurrent {
+		return errSessionsNeeded
+	}
+	return runMarked(test.markers, func() error {
+		return test.run(single(tx), test.filter)
+	})
+}
+
+// filter selects queries of preamble and sections, which are not skipped.
+func (test *Test) filter(q query) bool {
+	return q.section == 0 || !test.sections[q.section-1].markers.skip
+}
+
+// runMarked calls run according to skip and todo markers.
+func runMarked(m markers, run func() error) error {
+	if m.skip {
+		return &SkipError{Reason: m.reason}
+	}
+	err := run()
+	if !m.todo {
+		return err
+	}
+	if err == nil {
+		return errTodoPasses
+	}
+	var se *SkipError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &SkipError{Reason: err.Error(), Todo: true}
+}
+
+// run queries of test, which are selected by filter.
+func (test *Test) run(ss *sessions, filter func(query) bool) error {
+	defer ss.stop()
+	var updates []update
+	var todos []string
+	// check returns error of query q, collecting errors of todo queries.
+	check := func(q query, err error) error {
+		if q.markers.todo {
+			if err == nil {
+				err = errTodoPasses
+			} else {
+				todos = append(todos, test.queryError(q, err).Error())
+				return nil
+			}
+		}
+		if err != nil {
+			return test.queryError(q, err)
+		}
+		return nil
+	}
+	// Values of variables are allocated per run, so runs of test and its sections are independent.
+	runCtx := withValues(test.context)
+	r := test.resetter(ss)
+	only := slices.ContainsFunc(test.queries, func(q query) bool { return q.markers.only && filter(q) })
 	for _, q := range test.queries {
-		err = q.querier.Query(test.context, tx)
+		if !filter(q) || q.markers.skip || only && !q.markers.only {
+			continue
+		}
+		ctx := runCtx
+		if err := r.enter(ctx, q); err != nil {
+			return test.queryError(q, err)
+		}
+		if q.reset {
+			if err := r.rollback(ctx); err != nil {
+				return test.queryError(q, err)
+			}
+			continue
+		}
+		if q.await {
+			p := ss.await(q.session)
+			if p == nil {
+				return test.queryError(q, fmt.Errorf("%w: %q", errAwaitNothing, q.session))
+			}
+			err := <-p.done
+			if err == nil {
+				err = test.checkNotices(ss.txs[p.q.session], p.q)
+			}
+			if err := check(p.q, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := ss.pending[q.session]; ok {
+			return test.queryError(q, fmt.Errorf("%w: %q", errSessionBusy, q.session))
+		}
+		tx, err := ss.tx(q.session)
 		if err != nil {
-			return fmt.Errorf(
-				"Query on lines %d:%d at bytes %d:%d fails: %v.\nQuery source: %s",
-				q.left.line+1, q.right.line+1, q.left.index, q.right.index, err, q.source,
-			)
+			return test.queryError(q, err)
+		}
+		drainNotices(tx)
+		if q.async {
+			err := ss.start(ctx, q, test.blockTimeout, func(ctx context.Context) error {
+				return test.measure(q, func() error { return test.query(ctx, tx, q) })
+			})
+			if err != nil {
+				return test.queryError(q, err)
+			}
+			continue
+		}
+		var u *update
+		if test.update != nil && !q.expanded {
+			u = &update{}
+			ctx = context.WithValue(ctx, ctxKeyUpdate, u)
+		}
+		err = test.measure(q, func() error { return test.query(ctx, tx, q) })
+		if err == nil {
+			err = test.checkNotices(tx, q)
+		}
+		if u != nil && u.recorded {
+			u.file = q.file
+			u.span[0] += q.left.index + q.offset
+			u.span[1] += q.left.index + q.offset
+			updates = append(updates, *u)
+		}
+		if err := check(q, err); err != nil {
+			return err
+		}
+	}
+	for _, p := range ss.pending {
+		return test.queryError(p.q, errNotAwaited)
+	}
+	if err := r.close(test.context); err != nil {
+		return err
+	}
+	if test.update != nil {
+		if err := test.update.apply(updates); err != nil {
+			return err
 		}
 	}
+	if len(todos) > 0 {
+		return &SkipError{Reason: strings.Join(todos, "\n"), Todo: true}
+	}
 	return nil
 }
 
+// query calls querier of q, isolating it in savepoint if it may fail.
+func (test *Test) query(ctx context.Context, tx Tx, q query) error {
+	if test.savepoints != nil {
+		if fq, ok := q.querier.(FailingQuerier); ok && fq.ExpectsError() || q.markers.todo {
+			return withSavepoint(ctx, tx, test.savepoints, q.querier)
+		}
+	}
+	return q.querier.Query(ctx, tx)
+}
+
+// queryError describes error of query with its position.
+func (test *Test) queryError(q query, err error) error {
+	var in string
+	if q.file != test.file {
+		in = " in " + q.file
+	}
+	var ae *assertError
+	if errors.As(err, &ae) {
+		ae.at = location(q.file, q.left.line+bytes.Count(q.source[:q.offset], []byte("\n")))
+	}
+	return fmt.Errorf(
+		"Query%s on lines %d:%d at bytes %d:%d fails: %v.\nQuery source: %s",
+		in, q.left.line+1, q.right.line+1, q.left.index, q.right.index, err, q.source,
+	)
+}
+
 type execQuerier struct {
 	src []byte
 }
 
 // Query implements Querier.
 func (e *execQuerier) Query(ctx context.Context, tx Tx) error {
-	if err := tx.Exec(ctx, string(e.src)); err != nil {
+	sql, err := expandVars(ctx, string(e.src))
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec(ctx, sql); err != nil {
 		return fmt.Errorf("Exec(): %v", err)
 	}
 	return nil
@@ -161,9 +562,10 @@ func (e *execQuerier) Query(ctx context.Context, tx Tx) error {
 
 var _ Querier = (*execQuerier)(nil)
 
-type option func(*parseConfig)
+// Option configures parsing of tests.
+type Option func(*parseConfig)
 
-func newParserConfig(opts ...option) *parseConfig {
+func newParserConfig(opts ...Option) *parseConfig {
 	p := new(parseConfig)
 	for _, opt := range opts {
 		opt(p)
@@ -171,20 +573,68 @@ func newParserConfig(opts ...option) *parseConfig {
 	if p.limit == 0 {
 		p.limit = 100
 	}
-	if p.delimiter == nil {
-		p.delimiter = defaultQueryDelimiter
+	if p.blockTimeout == 0 {
+		p.blockTimeout = defaultBlockTimeout
+	}
+	if p.plans == nil {
+		p.plans = PostgresPlans
 	}
 	if p.parsers == nil {
 		p.parsers = []QueryParser{
 			&define{},
 			&except{},
+			&expect{},
+			&let{},
+			&plan{dialect: p.plans},
 		}
 	}
+	p.parsers = append(p.parsers[:len(p.parsers):len(p.parsers)], p.extra...)
+	if p.delimiter == nil {
+		p.delimiter = p.directives().delimiter
+	}
 	return p
 }
 
+// directives returns keys of directives handled while parsing and declared by parsers.
+func (p *parseConfig) directives() directives {
+	d := make(directives)
+	for _, key := range []string{noticeKey, blockedKey, asyncKey, awaitKey, includeKey, sectionKey, sessionKey} {
+		d[strings.TrimSpace(key)] = directiveHeader
+	}
+	for _, parser := range p.parsers {
+		dp, ok := parser.(DirectiveParser)
+		if !ok {
+			continue
+		}
+		header, data := dp.Directives()
+		for _, key := range header {
+			d[key] = directiveHeader
+		}
+		for _, key := range data {
+			d[key] = directiveData
+		}
+	}
+	return d
+}
+
 type parseConfig struct {
 	limit     int
 	delimiter QueryDelimiter
 	parsers   []QueryParser
+	extra     []QueryParser
+
+	savepoints SavepointDialect
+	update     bool
+	file       string
+
+	sectionReset bool
+	// Current session while parsing.
+	session       string
+	blockTimeout  time.Duration
+	plans         PlanDialect
+	maxDuration   time.Duration
+	durations     func(QueryDuration)
+	strictNotices bool
+
+	templateData map[string]any
 }
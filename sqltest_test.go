@@ -95,6 +95,36 @@ func TestNew(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "parser(expect)",
+			src:  "expect [1]\nSELECT 1",
+			want: []query{
+				{
+					left:    position{index: 0},
+					right:   position{line: 1, index: 19},
+					source:  []byte("expect [1]\nSELECT 1"),
+					querier: &assertQuerier{query: "SELECT 1", want: "[1]"},
+				},
+			},
+		},
+		{
+			name: "exec-dollar-quoted",
+			src:  "CREATE FUNCTION f() AS $$\nSELECT 1;\n$$;\nSELECT 2",
+			want: []query{
+				{
+					left:    position{index: 0},
+					right:   position{line: 2, index: 38},
+					source:  []byte("CREATE FUNCTION f() AS $$\nSELECT 1;\n$$"),
+					querier: &execQuerier{src: []byte("CREATE FUNCTION f() AS $$\nSELECT 1;\n$$")},
+				},
+				{
+					left:    position{line: 3, index: 40},
+					right:   position{line: 3, index: 48},
+					source:  []byte("SELECT 2"),
+					querier: &execQuerier{src: []byte("SELECT 2")},
+				},
+			},
+		},
 		{
 			name: "parser(define+assert)",
 			src:  "define TEST\nSELECT 1;\n\nassert TEST [1]",
@@ -111,11 +141,11 @@ func TestNew(t *testing.T) {
 				if def == nil {
 					t.Fatal("Value(ctxKeyDefine) = nil")
 				}
-				mdef, ok := def.(map[string]string)
+				mdef, ok := def.(map[string]definition)
 				if !ok {
 					t.Fatalf("Value(ctxKeyDefine).(type) = %T", def)
 				}
-				if g, w := fmt.Sprintf("%v", mdef), "map[TEST:SELECT 1]"; g != w {
+				if g, w := fmt.Sprintf("%v", mdef), "map[TEST:{SELECT 1 []}]"; g != w {
 					t.Fatalf("Value(ctxKeyDefine) = %q, want %q", g, w)
 				}
 			},
@@ -193,3 +223,80 @@ func cmpPos(s *strings.Builder, key string, p position) {
 	s.WriteString(strconv.Itoa(p.index))
 	s.WriteRune(' ')
 }
+
+func Test_newParserConfig(t *testing.T) {
+	extra := &except{}
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{name: "default", want: 5},
+		{name: "parsers", opts: []Option{WithParsers(&define{})}, want: 1},
+		{name: "extra-parsers", opts: []Option{WithExtraParsers(extra)}, want: 6},
+		{name: "parsers+extra", opts: []Option{WithExtraParsers(extra), WithParsers(&define{})}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newParserConfig(tt.opts...)
+			if g := len(got.parsers); g != tt.want {
+				t.Errorf("len(newParserConfig().parsers) = %d, want %d", g, tt.want)
+			}
+			if len(got.extra) > 0 && got.parsers[len(got.parsers)-1] != extra {
+				t.Error("newParserConfig().parsers does not end with extra parser")
+			}
+		})
+	}
+}
+
+// fakeTx is Tx which returns prepared results and errors for queries.
+type fakeTx struct {
+	rows map[string]fakeRows
+	// Typed values of rows, which are returned as ValueRows.
+	typed map[string][][]any
+	errs  map[string]error
+	execs []string
+}
+
+// Exec implements Tx.
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) error {
+	tx.execs = append(tx.execs, sql)
+	return tx.errs[sql]
+}
+
+// Query implements Tx.
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	if err := tx.errs[sql]; err != nil {
+		return nil, err
+	}
+	if values, ok := tx.typed[sql]; ok {
+		return &fakeValueRows{values: values, i: -1}, nil
+	}
+	rows := tx.rows[sql]
+	rows.i = -1
+	if rows.columns != nil {
+		return &fakeColumnRows{rows}, nil
+	}
+	return &rows, nil
+}
+
+var _ Tx = (*fakeTx)(nil)
+
+type fakeRows struct {
+	columns []string
+	values  []string
+	i       int
+}
+
+func (r *fakeRows) Close()                  {}
+func (r *fakeRows) Err() error              { return nil }
+func (r *fakeRows) Next() bool              { r.i++; return r.i < len(r.values) }
+func (r *fakeRows) String() (string, error) { return r.values[r.i], nil }
+
+type fakeColumnRows struct {
+	fakeRows
+}
+
+func (r *fakeColumnRows) Columns() ([]string, error) { return r.columns, nil }
+
+var _ ColumnRows = (*fakeColumnRows)(nil)
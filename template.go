@@ -0,0 +1,72 @@
+package sqltest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Expand {{.Name}} placeholders in queries with values of data before parsing.
+// Placeholders are expanded with [text/template], referencing missing keys is an error.
+func WithTemplateData(data map[string]any) Option {
+	return func(pc *parseConfig) {
+		if pc.templateData == nil {
+			pc.templateData = make(map[string]any, len(data))
+		}
+		for k, v := range data {
+			pc.templateData[k] = v
+		}
+	}
+}
+
+// Expand {{.NAME}} placeholders in queries with values of allowed environment variables.
+// Unset variables are missing keys, referencing them is an error.
+func WithEnv(names ...string) Option {
+	return func(pc *parseConfig) {
+		if pc.templateData == nil {
+			pc.templateData = make(map[string]any, len(names))
+		}
+		for _, name := range names {
+			if v, ok := os.LookupEnv(name); ok {
+				pc.templateData[name] = v
+			}
+		}
+	}
+}
+
+// expandTemplate executes query source as template with data.
+// Only {{.Name}} actions are expanded, other {{ are kept as is, like in array literal '{{1,2},{3,4}}'.
+func expandTemplate(src []byte, data map[string]any) ([]byte, error) {
+	if !bytes.Contains(src, []byte("{{.")) {
+		return src, nil
+	}
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(escapeTemplate(src))
+	if err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// escapeTemplate escapes {{, which does not start {{.Name}} action, to be printed as is.
+func escapeTemplate(src []byte) string {
+	b := &strings.Builder{}
+	for {
+		i := bytes.Index(src, []byte("{{"))
+		if i < 0 {
+			b.Write(src)
+			return b.String()
+		}
+		b.Write(src[:i])
+		src = src[i+2:]
+		if len(src) > 1 && src[0] == '.' && (src[1] == '_' || src[1] >= 'a' && src[1] <= 'z' || src[1] >= 'A' && src[1] <= 'Z') {
+			b.WriteString("{{")
+		} else {
+			b.WriteString(`{{"{{"}}`)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package sqltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_template(t *testing.T) {
+	t.Setenv("SQLTEST_TENANT", "42")
+	tests := []struct {
+		name    string
+		opts    []Option
+		src     string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			src:  "SELECT {{.Schema}}",
+			want: "{l: 0.0 r: 0.18 SELECT {{.Schema}} *execQuerier{SELECT {{.Schema}}}}",
+		},
+		{
+			name: "data",
+			opts: []Option{WithTemplateData(map[string]any{"Schema": "tenant"})},
+			src:  "SELECT 1;\nSELECT * FROM {{.Schema}}.emp",
+			want: "{l: 0.0 r: 0.8 SELECT 1 *execQuerier{SELECT 1}}" +
+				"  {l: 1.10 r: 1.39 SELECT * FROM tenant.emp *execQuerier{SELECT * FROM tenant.emp}}",
+		},
+		{
+			name: "env",
+			opts: []Option{WithEnv("SQLTEST_TENANT", "SQLTEST_UNSET")},
+			src:  "expect [{{.SQLTEST_TENANT}}]\nSELECT {{.SQLTEST_TENANT}}",
+			want: "{l: 0.0 r: 1.55 expect [42]\nSELECT 42 *assertQuerier{query: SELECT 42, want: [42]}}",
+		},
+		{
+			name: "array-literal",
+			opts: []Option{WithTemplateData(map[string]any{"Schema": "tenant"})},
+			src:  "SELECT '{{1,2},{3,4}}'::int[];\nINSERT INTO {{.Schema}}.t VALUES ('{{.5,1}}')",
+			want: "{l: 0.0 r: 0.29 SELECT '{{1,2},{3,4}}'::int[] *execQuerier{SELECT '{{1,2},{3,4}}'::int[]}}" +
+				"  {l: 1.31 r: 1.76 INSERT INTO tenant.t VALUES ('{{.5,1}}') *execQuerier{INSERT INTO tenant.t VALUES ('{{.5,1}}')}}",
+		},
+		{
+			name:    "env-not-allowed",
+			opts:    []Option{WithEnv("SQLTEST_UNSET")},
+			src:     "SELECT 1;\nSELECT {{.SQLTEST_TENANT}}",
+			wantErr: `line 2: template: query:1:9: executing "query" at <.SQLTEST_TENANT>: map has no entry for key "SQLTEST_TENANT"`,
+		},
+		{
+			name:    "syntax",
+			opts:    []Option{WithTemplateData(nil)},
+			src:     "SELECT 1;\n\nSELECT {{.Schema",
+			wantErr: `line 3: template: query:1: unclosed action`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := New(strings.NewReader(tt.src), tt.opts...)
+			if gotErr != nil {
+				if g := gotErr.Error(); g != tt.wantErr {
+					t.Errorf("New() error = %q, want %q", g, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatal("New() succeeded unexpectedly")
+			}
+			if g := cmpQueries(got.queries); g != tt.want {
+				t.Errorf("New() = %q, want %q", g, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+SELECT 1;
+include fixtures/broken.sql;
@@ -0,0 +1,2 @@
+SELECT 1;
+include cycle_b.sql;
@@ -0,0 +1,3 @@
+SELECT 1;
+
+assert undefined [1];
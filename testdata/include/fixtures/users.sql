@@ -0,0 +1,4 @@
+INSERT INTO users VALUES (1), (2);
+
+define user_count
+SELECT count(*) FROM users;
@@ -0,0 +1,3 @@
+include fixtures/users.sql;
+
+assert user_count [2];
@@ -0,0 +1,157 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var updateFlag = flag.Bool("sqltest.update", false, "rewrite expected values in sqltest files with actual results")
+
+var errUpdateNoFile = errors.New("update mode requires name of test file")
+
+// Rewrite expected values of asserts in test file with actual results instead of comparing them.
+// Update mode is also enabled by -sqltest.update flag.
+func WithUpdate(update bool) Option {
+	return func(pc *parseConfig) {
+		pc.update = update
+	}
+}
+
+// Set name of file from which test is read.
+// By default it is taken from reader, if it has Name method like [os.File].
+func WithFilename(name string) Option {
+	return func(pc *parseConfig) {
+		pc.file = name
+	}
+}
+
+// update is an actual value recorded by querier in update mode.
+type update struct {
+	file     string
+	recorded bool
+	span     [2]int
+	value    string
+}
+
+// updateFrom returns update recorder, if update mode is enabled.
+func updateFrom(ctx context.Context) *update {
+	u, _ := ctx.Value(ctxKeyUpdate).(*update)
+	return u
+}
+
+// record replacement of expected value at span of parsed source.
+func (u *update) record(span [2]int, value string) {
+	u.recorded = true
+	u.span = span
+	u.value = value
+}
+
+// updater rewrites files with values recorded by runs of test.
+// Values are applied to sources captured while parsing, so runs of test and its sections
+// may update the same file one after another.
+type updater struct {
+	mu sync.Mutex
+	// Parsed sources of files.
+	sources map[string][]byte
+	// Recorded values of files by spans of their sources.
+	values map[string]map[[2]int]string
+}
+
+func newUpdater() *updater {
+	return &updater{sources: make(map[string][]byte), values: make(map[string]map[[2]int]string)}
+}
+
+// apply adds recorded values to values of previous runs and rewrites updated files.
+func (up *updater) apply(updates []update) error {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	files := make(map[string]bool)
+	for _, u := range updates {
+		if up.values[u.file] == nil {
+			up.values[u.file] = make(map[[2]int]string)
+		}
+		up.values[u.file][u.span] = u.value
+		files[u.file] = true
+	}
+	for name := range files {
+		if err := up.rewriteFile(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteFile replaces spans of parsed source of file with recorded values.
+func (up *updater) rewriteFile(name string) error {
+	if name == "" {
+		return errUpdateNoFile
+	}
+	src, ok := up.sources[name]
+	if !ok {
+		return fmt.Errorf("update %s: source of file is not parsed", name)
+	}
+	spans := slices.Collect(maps.Keys(up.values[name]))
+	slices.SortFunc(spans, func(a, b [2]int) int { return b[0] - a[0] })
+	for _, span := range spans {
+		if span[0] > span[1] || span[1] > len(src) {
+			return fmt.Errorf("update %s: span %d:%d is out of file", name, span[0], span[1])
+		}
+		src = slices.Concat(src[:span[0]], []byte(up.values[name][span]), src[span[1]:])
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, src, info.Mode())
+}
+
+// formatTable returns psql-like table of rows, split into cells.
+func formatTable(columns []string, rs []row) (string, error) {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = len(c)
+	}
+	rows := make([][]string, len(rs))
+	for i, r := range rs {
+		rows[i] = r.cells(len(columns))
+		if len(rows[i]) != len(columns) {
+			return "", fmt.Errorf("can not split row %s into %d columns", r.text, len(columns))
+		}
+		for j, c := range rows[i] {
+			widths[j] = max(widths[j], len(c))
+		}
+	}
+	b := &bytes.Buffer{}
+	writeTableRow(b, columns, widths)
+	b.WriteByte('\n')
+	for i, w := range widths {
+		if i > 0 {
+			b.WriteByte('+')
+		}
+		b.WriteString(strings.Repeat("-", w+2))
+	}
+	for _, r := range rows {
+		b.WriteByte('\n')
+		writeTableRow(b, r, widths)
+	}
+	return b.String(), nil
+}
+
+func writeTableRow(b *bytes.Buffer, cells []string, widths []int) {
+	line := &strings.Builder{}
+	for i, c := range cells {
+		if i > 0 {
+			line.WriteString(" |")
+		}
+		fmt.Fprintf(line, " %-*s", widths[i], c)
+	}
+	b.WriteString(strings.TrimRight(line.String(), " "))
+}
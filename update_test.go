@@ -0,0 +1,162 @@
+package sqltest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithUpdate(t *testing.T) {
+	src := `-- comment before define
+define A
+SELECT a;
+
+define B
+SELECT b;
+
+/* values */ assert A  [old]  ;
+
+-- block
+assert B
+[old 1]
+[old 2];
+
+assert B
+ x | y
+---+---
+ 0 | 0;
+
+expect  [old]
+SELECT a;
+
+assert_unordered B [old];
+
+assert_unordered B
+[old];
+`
+	want := `-- comment before define
+define A
+SELECT a;
+
+define B
+SELECT b;
+
+/* values */ assert A  [1 a]  ;
+
+-- block
+assert B
+[1 b]
+[2 bb];
+
+assert B
+ x | y
+---+----
+ 1 | b
+ 2 | bb;
+
+expect  [1 a]
+SELECT a;
+
+assert_unordered B [1 b] [2 bb];
+
+assert_unordered B
+[1 b]
+[2 bb];
+`
+	name := filepath.Join(t.TempDir(), "test.sql")
+	if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	test, err := New(f, WithUpdate(true))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{rows: map[string]fakeRows{
+		"SELECT a": {values: []string{"[1 a]"}},
+		"SELECT b": {values: []string{"[1 b]", "[2 bb]"}},
+	}}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Run() updates file to:\n%s\nwant:\n%s", got, want)
+	}
+	test, err = New(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("New() of updated file failed: %v", err)
+	}
+	if err := test.Run(tx); err != nil {
+		t.Errorf("Run() of updated file failed: %v", err)
+	}
+}
+
+func TestWithUpdate_noFile(t *testing.T) {
+	test, err := New(strings.NewReader("define A\nSELECT a;\nassert A [old]"), WithUpdate(true))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{rows: map[string]fakeRows{"SELECT a": {values: []string{"[1]"}}}}
+	if err := test.Run(tx); err == nil {
+		t.Fatal("Run() succeeded unexpectedly")
+	}
+}
+
+func Test_formatTable(t *testing.T) {
+	got, err := formatTable([]string{"id", "name"}, textRows("[1 a]", "[22 bbb]"))
+	if err != nil {
+		t.Fatalf("formatTable() failed: %v", err)
+	}
+	want := " id | name\n----+------\n 1  | a\n 22 | bbb"
+	if got != want {
+		t.Errorf("formatTable() = %q, want %q", got, want)
+	}
+	got, err = formatTable([]string{"name", "id"}, []row{{text: "[John Smith 1]", values: []any{"John Smith", 1}}})
+	if err != nil {
+		t.Fatalf("formatTable() failed: %v", err)
+	}
+	if w := " name       | id\n------------+----\n John Smith | 1"; got != w {
+		t.Errorf("formatTable() = %q, want %q", got, w)
+	}
+	if _, err := formatTable([]string{"id"}, textRows("[1 a]")); err == nil {
+		t.Error("formatTable() succeeded unexpectedly")
+	}
+}
+
+func TestWithUpdate_sections(t *testing.T) {
+	src := "define A\nSELECT a;\n\ntest \"first\";\nassert A [1];\n\ntest \"second\";\nassert A\n[2];\n"
+	want := "define A\nSELECT a;\n\ntest \"first\";\nassert A [long value here];\n\ntest \"second\";\nassert A\n[long value here];\n"
+	name := filepath.Join(t.TempDir(), "test.sql")
+	if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	test, err := New(strings.NewReader(src), WithFilename(name), WithUpdate(true))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{rows: map[string]fakeRows{"SELECT a": {values: []string{"[long value here]"}}}}
+	for section, runner := range test.Sections() {
+		if err := runner.Run(tx); err != nil {
+			t.Fatalf("Run() of section %q failed: %v", section, err)
+		}
+	}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Run() updates file to:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package sqltest
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// FormatValue returns canonical representation of value returned by [ValueRows].
+//
+// NULL is formatted as NULL, times in UTC as RFC 3339, byte slices as hex with \x prefix,
+// maps and other slices as JSON. Implementations of driver.Valuer are formatted by their values.
+func FormatValue(v any) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		v = dv
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case fmt.Stringer:
+		return v.String()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return FormatValue(rv.Elem().Interface())
+	case reflect.Map, reflect.Slice, reflect.Array:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// formatValues returns canonical representation of row values,
+// in the same form as fmt prints slice of values.
+func formatValues(values []any) string {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = FormatValue(v)
+	}
+	return formatCells(cells)
+}
+
+// readRow returns current row.
+// Values of [ValueRows] are formatted canonically, other rows represent themselves.
+func readRow(rows Rows) (row, error) {
+	vr, ok := rows.(ValueRows)
+	if !ok {
+		s, err := rows.String()
+		return row{text: s}, err
+	}
+	values, err := vr.Values()
+	if err != nil {
+		return row{}, err
+	}
+	if values == nil {
+		values = []any{}
+	}
+	return row{text: formatValues(values), values: values}, nil
+}
+
+// rowText returns text of current row without brackets.
+// Single value of [ValueRows] is not formatted, so text and byte slices are kept as is.
+func rowText(rows Rows) (string, error) {
+	vr, ok := rows.(ValueRows)
+	if !ok {
+		r, err := rows.String()
+		return trimBrackets(r), err
+	}
+	values, err := vr.Values()
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 1 {
+		switch v := values[0].(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	}
+	return trimBrackets(formatValues(values)), nil
+}
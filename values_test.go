@@ -0,0 +1,119 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatValue(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	s := "text"
+	var null *string
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "null", v: nil, want: "NULL"},
+		{name: "string", v: "a b", want: "a b"},
+		{name: "int", v: int64(-1), want: "-1"},
+		{name: "float", v: 1.5, want: "1.5"},
+		{name: "bool", v: true, want: "true"},
+		{name: "bytes", v: []byte{0xde, 0xad}, want: `\xdead`},
+		{name: "time", v: time.Date(2024, 1, 2, 3, 4, 5, 0, moscow), want: "2024-01-02T00:04:05Z"},
+		{name: "time-fraction", v: time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC), want: "2024-01-02T03:04:05.5Z"},
+		{name: "pointer", v: &s, want: "text"},
+		{name: "nil-pointer", v: null, want: "NULL"},
+		{name: "valuer", v: sql.NullInt64{Int64: 1, Valid: true}, want: "1"},
+		{name: "valuer-null", v: sql.NullString{}, want: "NULL"},
+		{name: "json", v: map[string]any{"a": []any{1, "b"}}, want: `{"a":[1,"b"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatValue(tt.v); got != tt.want {
+				t.Errorf("FormatValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeValueRows returns values of rows instead of their representations.
+type fakeValueRows struct {
+	values [][]any
+	i      int
+}
+
+func (r *fakeValueRows) Close()                  {}
+func (r *fakeValueRows) Err() error              { return nil }
+func (r *fakeValueRows) Next() bool              { r.i++; return r.i < len(r.values) }
+func (r *fakeValueRows) String() (string, error) { return "driver defined", nil }
+func (r *fakeValueRows) Values() ([]any, error)  { return r.values[r.i], nil }
+
+var _ ValueRows = (*fakeValueRows)(nil)
+
+// valueTx returns prepared values for any query.
+type valueTx struct {
+	fakeTx
+	values [][]any
+}
+
+func (tx *valueTx) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	return &fakeValueRows{values: tx.values, i: -1}, nil
+}
+
+func Test_queryRows_values(t *testing.T) {
+	tx := &valueTx{values: [][]any{
+		{1, nil, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{2, []byte("a"), time.Time{}},
+	}}
+	_, rs, err := queryRows(context.Background(), tx, "SELECT")
+	if err != nil {
+		t.Fatalf("queryRows() failed: %v", err)
+	}
+	want := "[1 NULL 2024-01-02T03:04:05Z]; [2 \\x61 0001-01-01T00:00:00Z]"
+	if g := strings.Join(rowTexts(rs), "; "); g != want {
+		t.Errorf("queryRows() = %q, want %q", g, want)
+	}
+}
+
+func Test_queryTexts(t *testing.T) {
+	tx := &valueTx{values: [][]any{
+		{[]byte(`{"a": "[b c]"}`)},
+		{map[string]any{"a": 1}},
+		{"Seq Scan on emp"},
+		{1, nil},
+	}}
+	got, err := queryTexts(context.Background(), tx, "SELECT")
+	if err != nil {
+		t.Fatalf("queryTexts() failed: %v", err)
+	}
+	want := `{"a": "[b c]"}; {"a":1}; Seq Scan on emp; 1 NULL`
+	if g := strings.Join(got, "; "); g != want {
+		t.Errorf("queryTexts() = %q, want %q", g, want)
+	}
+	got, err = queryTexts(context.Background(), &fakeTx{rows: map[string]fakeRows{"SELECT": {values: []string{"[[1 2]]"}}}}, "SELECT")
+	if err != nil {
+		t.Fatalf("queryTexts() failed: %v", err)
+	}
+	if g, w := strings.Join(got, "; "), "[1 2]"; g != w {
+		t.Errorf("queryTexts() = %q, want %q", g, w)
+	}
+}
+
+func TestTest_Run_values(t *testing.T) {
+	src := "define created\nSELECT created_at, deleted_at FROM emp;\n" +
+		"assert created [2024-01-02T03:04:05Z NULL];\n" +
+		"assert created\ncreated_at           | deleted_at\n---------------------+-----------\n2024-01-02T03:04:05Z | NULL"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	moscow := time.FixedZone("MSK", 3*60*60)
+	tx := &valueTx{values: [][]any{{time.Date(2024, 1, 2, 6, 4, 5, 0, moscow), nil}}}
+	if err := test.Run(tx); err != nil {
+		t.Errorf("Run() failed: %v", err)
+	}
+}
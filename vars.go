@@ -0,0 +1,261 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// let parses statements which capture query results into variables.
+type let struct{}
+
+const (
+	letKey = "let "
+	letLen = len(letKey)
+)
+
+var (
+	errLetMissName  = errors.New("missing variable name in let statement")
+	errLetMissQuery = errors.New("missing query in let statement")
+	errLetName      = errors.New("malformed variable name in let statement")
+	errLetRows      = errors.New("let query should return exactly one row")
+	errLetCells     = errors.New("let query returns row which does not match variables count")
+	errLetValues    = errors.New("let of several variables requires Rows implementing ValueRows")
+	errVarUndef     = errors.New("variable is not defined")
+	errVarUnclosed  = errors.New("unclosed variable reference")
+)
+
+// variables of test, which are declared while parsing.
+type variables struct {
+	declared map[string]bool
+}
+
+func varsFrom(ctx context.Context) *variables {
+	v, _ := ctx.Value(ctxKeyVars).(*variables)
+	return v
+}
+
+// varValues are values of variables, which are set by let statements during a single run.
+// They are guarded by mutex, as statements of sessions may run in background.
+type varValues struct {
+	mu sync.Mutex
+	m  map[string]any
+}
+
+// withValues returns context with empty values of variables for a new run.
+func withValues(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyValues, &varValues{m: make(map[string]any)})
+}
+
+func valuesFrom(ctx context.Context) *varValues {
+	v, _ := ctx.Value(ctxKeyValues).(*varValues)
+	return v
+}
+
+func (v *varValues) get(name string) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	val, ok := v.m[name]
+	return val, ok
+}
+
+func (v *varValues) set(name string, val any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.m[name] = val
+}
+
+// Parse implements QueryParser.
+//
+// Statement let a = query declares variable a with scalar result of query,
+// let a, b = query declares variables with values of the returned row.
+func (l *let) Parse(ctx context.Context, src []byte) (context.Context, Querier, error) {
+	if !bytes.HasPrefix(src, []byte(letKey)) {
+		return nil, nil, nil
+	}
+	lhs, que, ok := strings.Cut(string(src[letLen:]), "=")
+	if !ok || strings.ContainsRune(lhs, '\n') {
+		return nil, nil, errLetMissName
+	}
+	que = strings.Trim(que, " \t\r\n")
+	if que == "" {
+		return nil, nil, errLetMissQuery
+	}
+	var names []string
+	for name := range strings.SplitSeq(lhs, ",") {
+		name = strings.Trim(name, " \t")
+		if name == "" {
+			return nil, nil, errLetMissName
+		}
+		for i := 0; i < len(name); i++ {
+			if !isIdentChar(name[i]) || name[i] == '$' {
+				return nil, nil, fmt.Errorf("%w: %q", errLetName, name)
+			}
+		}
+		names = append(names, name)
+	}
+	v := varsFrom(ctx)
+	if v == nil {
+		v = &variables{declared: make(map[string]bool)}
+		ctx = context.WithValue(ctx, ctxKeyVars, v)
+	}
+	if err := checkVars(ctx, que); err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		v.declared[name] = true
+	}
+	return ctx, &letQuerier{names: names, query: que}, nil
+}
+
+// Directives implements DirectiveParser.
+func (l *let) Directives() (header, data []string) {
+	return []string{strings.TrimSpace(letKey)}, nil
+}
+
+var _ DirectiveParser = (*let)(nil)
+
+type letQuerier struct {
+	names []string
+	query string
+}
+
+// Query implements Querier.
+func (l *letQuerier) Query(ctx context.Context, tx Tx) error {
+	sql, err := expandVars(ctx, l.query)
+	if err != nil {
+		return err
+	}
+	_, rs, err := queryRows(ctx, tx, sql)
+	if err != nil {
+		return err
+	}
+	if len(rs) != 1 {
+		return fmt.Errorf("%w, got %d", errLetRows, len(rs))
+	}
+	v := valuesFrom(ctx)
+	values := rs[0].values
+	if values == nil {
+		// Representation of row can not be split into values reliably.
+		if len(l.names) > 1 {
+			return errLetValues
+		}
+		v.set(l.names[0], trimBrackets(rs[0].text))
+		return nil
+	}
+	if len(values) != len(l.names) {
+		return fmt.Errorf("%w: %s", errLetCells, rs[0].text)
+	}
+	for i, name := range l.names {
+		v.set(name, values[i])
+	}
+	return nil
+}
+
+var _ Querier = (*letQuerier)(nil)
+
+// varRefs calls fn for each ${name} reference in src with its bounds.
+// If src is SQL, references inside string literals, quoted identifiers
+// and dollar quoted strings are not variables.
+func varRefs(src string, sql bool, fn func(start, end int, name string) error) error {
+	for i := 0; i < len(src); i++ {
+		if sql {
+			switch c := src[i]; c {
+			case '\'', '"':
+				i, _ = skipQuoted([]byte(src), i+1, 0, c, false)
+				continue
+			case '$':
+				if tag := dollarTag([]byte(src[i:])); tag != nil && (i == 0 || !isIdentChar(src[i-1])) {
+					i, _ = skipDollarQuoted([]byte(src), i+len(tag), 0, tag)
+					continue
+				}
+			}
+		}
+		if src[i] != '$' || i+1 == len(src) || src[i+1] != '{' {
+			continue
+		}
+		r := strings.IndexByte(src[i:], '}')
+		if r < 0 {
+			return errVarUnclosed
+		}
+		if err := fn(i, i+r+1, strings.Trim(src[i+2:i+r], " ")); err != nil {
+			return err
+		}
+		i += r
+	}
+	return nil
+}
+
+// checkVars checks that all variables referenced in SQL src are declared.
+func checkVars(ctx context.Context, src string) error {
+	if !strings.Contains(src, "${") {
+		return nil
+	}
+	v := varsFrom(ctx)
+	return varRefs(src, true, func(_, _ int, name string) error {
+		if v == nil || !v.declared[name] {
+			return fmt.Errorf("%w: %s", errVarUndef, name)
+		}
+		return nil
+	})
+}
+
+// expandVars replaces ${name} references in sql with SQL literals of variables values.
+func expandVars(ctx context.Context, sql string) (string, error) {
+	return replaceVars(ctx, sql, true, sqlLiteral)
+}
+
+// replaceVars replaces ${name} references in src with formatted values of variables.
+// If src is SQL, quoted references are kept as is.
+func replaceVars(ctx context.Context, src string, sql bool, format func(any) string) (string, error) {
+	if !strings.Contains(src, "${") {
+		return src, nil
+	}
+	v := valuesFrom(ctx)
+	b := &strings.Builder{}
+	var last int
+	err := varRefs(src, sql, func(start, end int, name string) error {
+		val, ok := v.get(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", errVarUndef, name)
+		}
+		b.WriteString(src[last:start])
+		b.WriteString(format(val))
+		last = end
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+// sqlLiteral returns SQL literal of value.
+func sqlLiteral(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteLiteral(v)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return FormatValue(v)
+	default:
+		return quoteLiteral(FormatValue(v))
+	}
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// formatVar returns text of value for expected results, formatted the same way as returned values.
+func formatVar(v any) string {
+	return FormatValue(v)
+}
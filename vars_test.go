@@ -0,0 +1,181 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_let_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    *letQuerier
+		wantErr bool
+	}{
+		{name: "unrecognized", src: "SELECT 1"},
+		{name: "missing-eq", src: "let a\nSELECT 1", wantErr: true},
+		{name: "missing-name", src: "let = SELECT 1", wantErr: true},
+		{name: "missing-query", src: "let a = ", wantErr: true},
+		{name: "malformed-name", src: "let a b = SELECT 1", wantErr: true},
+		{name: "undefined-var", src: "let a = SELECT ${b}", wantErr: true},
+		{
+			name: "scalar",
+			src:  "let a = INSERT INTO t VALUES (1)\nRETURNING id",
+			want: &letQuerier{names: []string{"a"}, query: "INSERT INTO t VALUES (1)\nRETURNING id"},
+		},
+		{
+			name: "row",
+			src:  "let a, b=SELECT 1, 2",
+			want: &letQuerier{names: []string{"a", "b"}, query: "SELECT 1, 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCtx, gotQue, gotErr := (&let{}).Parse(context.Background(), []byte(tt.src))
+			if gotErr != nil {
+				if !tt.wantErr {
+					t.Errorf("Parse() failed: %v", gotErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("Parse() succeeded unexpectedly")
+			}
+			if tt.want == nil {
+				if gotCtx != nil || gotQue != nil {
+					t.Errorf("Parse() = %v, %v, want nils", gotCtx, gotQue)
+				}
+				return
+			}
+			if g, w := fmt.Sprintf("%+v", gotQue), fmt.Sprintf("%+v", tt.want); g != w {
+				t.Errorf("Parse() querier = %s, want %s", g, w)
+			}
+			v := varsFrom(gotCtx)
+			for _, name := range tt.want.names {
+				if v == nil || !v.declared[name] {
+					t.Errorf("Parse() does not declare %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestTest_Run_vars(t *testing.T) {
+	src := `let uid = INSERT INTO emp VALUES (1) RETURNING user_id;
+let name, salary = SELECT name, salary FROM emp;
+
+define salary_of(uid)
+SELECT salary FROM emp WHERE user_id = $1;
+
+UPDATE emp SET name = ${name} WHERE user_id = ${uid};
+assert salary_of(${uid}) [${salary}];
+expect [${uid}]
+SELECT ${uid};
+except duplicate
+INSERT INTO emp VALUES (${ uid })`
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{
+		rows: map[string]fakeRows{
+			"INSERT INTO emp VALUES (1) RETURNING user_id": {values: []string{"[1]"}},
+			"SELECT salary FROM emp WHERE user_id = $1":    {values: []string{"[1000]"}},
+			"SELECT '1'": {values: []string{"[1]"}},
+		},
+		typed: map[string][][]any{"SELECT name, salary FROM emp": {{"o'hara", 1000}}},
+		errs:  map[string]error{"INSERT INTO emp VALUES ('1')": errors.New("duplicate key")},
+	}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	want := "UPDATE emp SET name = 'o''hara' WHERE user_id = '1'; INSERT INTO emp VALUES ('1')"
+	if g := strings.Join(tx.execs, "; "); g != want {
+		t.Errorf("Run() executes %q, want %q", g, want)
+	}
+}
+
+func TestTest_Run_varsValues(t *testing.T) {
+	src := "let a, b = SELECT a, b;\nlet c = SELECT c;\nUPDATE t SET a = ${a}, b = ${b}, c = ${c}"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{typed: map[string][][]any{
+		"SELECT a, b": {{"John Smith", nil}},
+		"SELECT c":    {{int64(1)}},
+	}}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if g, w := strings.Join(tx.execs, "; "), "UPDATE t SET a = 'John Smith', b = NULL, c = 1"; g != w {
+		t.Errorf("Run() executes %q, want %q", g, w)
+	}
+	tx = &fakeTx{rows: map[string]fakeRows{"SELECT a, b": {values: []string{"[John Smith <nil>]"}}}}
+	if err := test.Run(tx); err == nil || !strings.Contains(err.Error(), errLetValues.Error()) {
+		t.Errorf("Run() error = %v, want %v", err, errLetValues)
+	}
+}
+
+func TestNew_undefinedVar(t *testing.T) {
+	_, err := New(strings.NewReader("SELECT 1;\nUPDATE emp SET id = ${uid}"))
+	if !errors.Is(err, errVarUndef) {
+		t.Fatalf("New() error = %v, want %v", err, errVarUndef)
+	}
+	if g, w := err.Error(), "line 2: variable is not defined: uid"; g != w {
+		t.Errorf("New() error = %q, want %q", g, w)
+	}
+}
+
+func TestTest_Run_varsPerRun(t *testing.T) {
+	src := "test \"set\";\nlet uid = SELECT id;\nUPDATE emp SET id = ${uid};\n" +
+		"test \"use\";\nDELETE FROM emp WHERE id = ${uid}"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for _, id := range []string{"1", "2", "3"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := &fakeTx{rows: map[string]fakeRows{"SELECT id": {values: []string{"[" + id + "]"}}}}
+			if err := test.Run(tx); err != nil {
+				t.Errorf("Run() failed: %v", err)
+			}
+			want := "UPDATE emp SET id = '" + id + "'; DELETE FROM emp WHERE id = '" + id + "'"
+			if g := strings.Join(tx.execs, "; "); g != want {
+				t.Errorf("Run() executes %q, want %q", g, want)
+			}
+		}()
+	}
+	wg.Wait()
+	for name, runner := range test.Sections() {
+		if name != "use" {
+			continue
+		}
+		if err := runner.Run(&fakeTx{}); err == nil || !strings.Contains(err.Error(), errVarUndef.Error()) {
+			t.Errorf("Run() of section %q error = %v, want %v", name, err, errVarUndef)
+		}
+	}
+}
+
+func TestTest_Run_quotedVars(t *testing.T) {
+	src := "SELECT '${x}', \"${x}\";\nDO $fn$ BEGIN RAISE NOTICE '${x}'; END $fn$;\nlet x = SELECT x;\nSELECT '${x}', ${x}"
+	test, err := New(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tx := &fakeTx{rows: map[string]fakeRows{"SELECT x": {values: []string{"[1]"}}}}
+	if err := test.Run(tx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	want := "SELECT '${x}', \"${x}\"; DO $fn$ BEGIN RAISE NOTICE '${x}'; END $fn$; SELECT '${x}', '1'"
+	if g := strings.Join(tx.execs, "; "); g != want {
+		t.Errorf("Run() executes %q, want %q", g, want)
+	}
+}